@@ -26,7 +26,7 @@ if errorlevel 1 (
 
 REM Build the application
 echo Building application...
-go build -o markdown-to-html.exe cmd/main.go
+go build -o markdown-to-html.exe ./cmd
 if errorlevel 1 (
     echo Error: Build failed
     pause
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"markdown-to-html/pkg/mdconvert"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	bookOutput   string
+	bookSummary  string
+	bookTitle    string
+	bookTOCTitle string
+)
+
+func newBookCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "book [chapter.md...]",
+		Short: "Combine several markdown files into one PDF book",
+		Long: `Combines chapters into a single PDF with a title page, a table of
+contents, bookmarks and continuous page numbers. Every chapter starts on a
+new page and links between chapter files become links inside the PDF.
+
+Chapters are given in order on the command line or read from a SUMMARY.md
+manifest, whose first heading is used as the book title.
+
+Examples:
+  markdown-converter book intro.md usage.md faq.md -o guide.pdf --title "Guide"
+  markdown-converter book --summary docs/SUMMARY.md -o docs.pdf
+  markdown-converter book --summary SUMMARY.md --pdf-engine native`,
+		Run: runBook,
+	}
+
+	cmd.Flags().StringVarP(&bookOutput, "output", "o", "book.pdf", "Output PDF file")
+	cmd.Flags().StringVar(&bookSummary, "summary", "", "Read the chapter list from a SUMMARY.md manifest")
+	cmd.Flags().StringVar(&bookTitle, "title", "", "Book title (default: first heading of the summary)")
+	cmd.Flags().StringVar(&bookTOCTitle, "toc-title", mdconvert.DefaultTOCTitle, "Heading of the table of contents")
+	addConversionFlags(cmd)
+
+	return cmd
+}
+
+func runBook(cmd *cobra.Command, args []string) {
+	book := &mdconvert.Book{}
+	if bookSummary != "" {
+		if len(args) > 0 {
+			fmt.Println("Error: Pass chapters either as arguments or with --summary, not both")
+			os.Exit(1)
+		}
+		loaded, err := mdconvert.LoadSummary(bookSummary)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		book = loaded
+	} else {
+		if len(args) == 0 {
+			fmt.Println("Error: At least one chapter is required")
+			fmt.Println("Usage: markdown-converter book chapter1.md chapter2.md -o book.pdf")
+			os.Exit(1)
+		}
+		book.Chapters = args
+	}
+
+	if bookTitle != "" {
+		book.Title = bookTitle
+	}
+	book.TOCTitle = bookTOCTitle
+
+	for _, chapter := range book.Chapters {
+		if _, err := os.Stat(chapter); err != nil {
+			fmt.Printf("Error: Chapter '%s' does not exist\n", chapter)
+			os.Exit(1)
+		}
+	}
+
+	if pdfEngine == mdconvert.EngineWkhtmltopdf && !mdconvert.IsWkhtmltopdfInstalled() {
+		fmt.Println("Error: wkhtmltopdf is not installed")
+		fmt.Println(mdconvert.InstallInstructions())
+		os.Exit(1)
+	}
+
+	// The project config is looked up from the summary or first chapter
+	configDir := "."
+	if bookSummary != "" {
+		configDir = filepath.Dir(bookSummary)
+	} else if len(book.Chapters) > 0 {
+		configDir = filepath.Dir(book.Chapters[0])
+	}
+	opts, err := buildOptions(configDir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	renderer, err := mdconvert.NewPDFRenderer(pdfEngine)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Stop the conversion, including any external renderer, on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := mdconvert.ConvertBookToPDFContext(ctx, renderer, book, bookOutput, opts); err != nil {
+		fmt.Printf("Error creating book: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Successfully combined %d chapters into '%s'\n", len(book.Chapters), bookOutput)
+}
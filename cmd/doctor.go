@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"markdown-to-html/pkg/mdconvert"
+
+	"github.com/spf13/cobra"
+)
+
+// doctorReport collects check results and remembers whether any failed
+type doctorReport struct {
+	failed bool
+}
+
+func (d *doctorReport) ok(format string, args ...interface{}) {
+	fmt.Printf("  ✓ "+format+"\n", args...)
+}
+
+func (d *doctorReport) warn(format string, args ...interface{}) {
+	fmt.Printf("  ! "+format+"\n", args...)
+}
+
+func (d *doctorReport) fail(format string, args ...interface{}) {
+	d.failed = true
+	fmt.Printf("  ✗ "+format+"\n", args...)
+}
+
+func newDoctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment needed for PDF output",
+		Long: `Reports the wkhtmltopdf binary, its version and Qt build, the fonts
+available to both PDF engines, the diagram tools and whether the CDN
+assets used by the HTML wrapper are reachable.`,
+		Args: cobra.NoArgs,
+		Run:  runDoctor,
+	}
+}
+
+func runDoctor(cmd *cobra.Command, args []string) {
+	report := &doctorReport{}
+
+	checkWkhtmltopdf(report)
+	checkFonts(report)
+	checkDiagrams(report)
+	checkNetwork(report)
+
+	fmt.Println()
+	if report.failed {
+		fmt.Println("Some checks failed. PDF output with the wkhtmltopdf engine may not work;")
+		fmt.Println("use --pdf-engine native or fix the issues above.")
+		os.Exit(1)
+	}
+	fmt.Println("All checks passed.")
+}
+
+func checkWkhtmltopdf(report *doctorReport) {
+	fmt.Println("wkhtmltopdf")
+
+	info, err := mdconvert.DetectWkhtmltopdf()
+	if err != nil {
+		report.fail("binary: %v", err)
+		fmt.Println()
+		fmt.Println(indent(mdconvert.InstallInstructions(), "    "))
+		fmt.Println()
+		return
+	}
+	report.ok("binary: %s (%s)", info.Path, info.Source)
+
+	if info.Version == "" {
+		report.warn("version: unknown")
+	} else {
+		report.ok("version: %s", info.Version)
+	}
+
+	if info.PatchedQt {
+		report.ok("patched Qt: yes")
+	} else {
+		report.warn("patched Qt: no (headers, footers, outlines and TOC are unavailable)")
+	}
+
+	// wkhtmltoimage is only needed for image output
+	if image, err := mdconvert.DetectWkhtmltoimage(); err != nil {
+		report.warn("wkhtmltoimage: %v (image output unavailable)", err)
+	} else {
+		report.ok("wkhtmltoimage: %s", image.Path)
+	}
+
+	// Render a tiny document to make sure the binary actually works
+	start := time.Now()
+	renderer, _ := mdconvert.NewPDFRenderer(mdconvert.EngineWkhtmltopdf)
+	if err := mdconvert.RenderPDF(context.Background(), renderer, io.Discard, "# doctor\n\nÇalışıyor.", mdconvert.Options{Theme: mdconvert.ThemeLight}); err != nil {
+		report.fail("test render: %v", err)
+	} else {
+		report.ok("test render: ok (%s)", time.Since(start).Round(time.Millisecond))
+	}
+	fmt.Println()
+}
+
+func checkFonts(report *doctorReport) {
+	fmt.Println("fonts")
+
+	fonts := mdconvert.NativeFonts()
+	roles := make([]string, 0, len(fonts))
+	for role := range fonts {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	if len(roles) == 0 {
+		report.warn("native engine: no TrueType fonts found, using core fonts (no Turkish/CJK glyphs)")
+	}
+	for _, role := range roles {
+		report.ok("native %s: %s", role, fonts[role])
+	}
+
+	// wkhtmltopdf resolves fonts through fontconfig outside Windows
+	if runtime.GOOS != "windows" {
+		out, err := exec.Command("fc-list", ":lang=tr", "family").Output()
+		switch {
+		case err != nil:
+			report.warn("fontconfig: fc-list not available (%v)", err)
+		case strings.TrimSpace(string(out)) == "":
+			report.warn("fontconfig: no fonts with Turkish coverage")
+		default:
+			families := strings.Split(strings.TrimSpace(string(out)), "\n")
+			report.ok("fontconfig: %d font families with Turkish coverage", len(families))
+		}
+	}
+	fmt.Println()
+}
+
+// checkDiagrams reports which diagram commands can be found; missing ones
+// only leave diagram fences as code
+func checkDiagrams(report *doctorReport) {
+	fmt.Println("diagrams")
+
+	commands := mdconvert.DefaultDiagrams()
+	languages := make([]string, 0, len(commands))
+	for language := range commands {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		name := strings.Fields(commands[language])[0]
+		if path, err := exec.LookPath(name); err != nil {
+			report.warn("%s: %s not found (fences stay code blocks)", language, name)
+		} else {
+			report.ok("%s: %s", language, path)
+		}
+	}
+	report.ok("cache: %s", mdconvert.DefaultDiagramCache())
+	fmt.Println()
+}
+
+func checkNetwork(report *doctorReport) {
+	fmt.Println("network")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	for _, url := range mdconvert.CDNResources() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			cancel()
+			report.fail("%s: %v", url, err)
+			continue
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		cancel()
+		if err != nil {
+			report.warn("%s: unreachable (%v)", url, err)
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 400 {
+			report.warn("%s: HTTP %d", url, resp.StatusCode)
+		} else {
+			report.ok("%s (%s)", url, time.Since(start).Round(time.Millisecond))
+		}
+	}
+}
+
+// indent prefixes every line of s
+func indent(s string, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"markdown-to-html/internal/converter"
 	"markdown-to-html/internal/utils"
+	"markdown-to-html/pkg/mdconvert"
 
 	"github.com/spf13/cobra"
 )
@@ -18,6 +23,49 @@ var (
 	preview    bool
 	theme      string
 	format     string
+	pdfEngine  string
+
+	wkhtmltopdfPath string
+
+	fontDir      string
+	fontBody     string
+	fontHeadings string
+	fontCode     string
+	embedFonts   bool
+
+	breakBefore string
+	extensions  string
+	copyButtons bool
+	filters     []string
+
+	diagrams     []string
+	diagramCache string
+
+	configFile  string
+	includeRoot string
+
+	watermarkText     string
+	watermarkImage    string
+	watermarkOpacity  float64
+	watermarkRotation float64
+	watermarkPosition string
+
+	docTitle    string
+	docAuthor   string
+	docSubject  string
+	docKeywords string
+	docLanguage string
+
+	imageFormat  string
+	imageWidth   int
+	imageQuality int
+	cropX        int
+	cropY        int
+	cropWidth    int
+	cropHeight   int
+	firstPage    bool
+
+	timeout time.Duration
 )
 
 func main() {
@@ -31,14 +79,40 @@ Examples:
   markdown-converter input.md output.pdf --format pdf
   markdown-converter input.md --format pdf                    # Outputs to input.pdf
   markdown-converter input.md --preview                       # Shows HTML preview in terminal
-  markdown-converter input.md --theme dark --format pdf       # Uses dark theme for PDF`,
+  cat input.md | markdown-converter - --format pdf > out.pdf  # Streams stdin to stdout
+  markdown-converter input.md --theme dark --format pdf       # Uses dark theme for PDF
+  markdown-converter input.md --format pdf --pdf-engine native  # PDF without wkhtmltopdf
+  markdown-converter input.md --font-dir fonts --font-body "Noto Sans"  # Custom fonts
+  markdown-converter input.md --format pdf --watermark DRAFT  # Stamps every page
+  markdown-converter input.md --format image --first-page     # PNG thumbnail of the first page
+  markdown-converter book ch1.md ch2.md -o book.pdf           # Combines chapters into one PDF
+  markdown-converter site docs -o public                      # Links a folder of pages with [[wiki links]]
+  markdown-converter doctor                                   # Checks the PDF toolchain`,
 		Args: cobra.MaximumNArgs(2),
 		Run:  run,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if wkhtmltopdfPath != "" {
+				mdconvert.SetWkhtmltopdfPath(wkhtmltopdfPath)
+			}
+		},
 	}
 
 	rootCmd.Flags().BoolVarP(&preview, "preview", "p", false, "Show preview in terminal")
-	rootCmd.Flags().StringVarP(&theme, "theme", "t", "light", "Theme: light or dark")
-	rootCmd.Flags().StringVarP(&format, "format", "f", "html", "Output format: html or pdf")
+	rootCmd.Flags().StringVarP(&format, "format", "f", "html", "Output format: html, pdf or image")
+	rootCmd.Flags().StringVar(&imageFormat, "image-format", "", "Image format: png or jpg (default: from the output extension, else png)")
+	rootCmd.Flags().IntVar(&imageWidth, "image-width", mdconvert.DefaultImageWidth, "Image viewport width in pixels")
+	rootCmd.Flags().IntVar(&imageQuality, "image-quality", 0, "JPEG quality from 1 to 100")
+	rootCmd.Flags().IntVar(&cropX, "crop-x", 0, "Left edge of the image crop in pixels")
+	rootCmd.Flags().IntVar(&cropY, "crop-y", 0, "Top edge of the image crop in pixels")
+	rootCmd.Flags().IntVar(&cropWidth, "crop-width", 0, "Width of the image crop in pixels")
+	rootCmd.Flags().IntVar(&cropHeight, "crop-height", 0, "Height of the image crop in pixels")
+	rootCmd.Flags().BoolVar(&firstPage, "first-page", false, "Only render the first page into the image")
+	addConversionFlags(rootCmd)
+	rootCmd.PersistentFlags().StringVar(&wkhtmltopdfPath, "wkhtmltopdf", "", "Path to the wkhtmltopdf binary (overrides $"+mdconvert.EnvWkhtmltopdfBin+")")
+
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newBookCmd())
+	rootCmd.AddCommand(newSiteCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -51,82 +125,317 @@ func run(cmd *cobra.Command, args []string) {
 	if len(args) == 0 {
 		fmt.Println("Error: Input file is required")
 		fmt.Println("Usage: markdown-to-html input.md [output.html]")
+		fmt.Println("       cat input.md | markdown-to-html - > output.html")
 		os.Exit(1)
 	}
 
 	inputFile = args[0]
-	
+
 	if len(args) == 2 {
 		outputFile = args[1]
+	} else if inputFile == "-" {
+		// Piped input goes to stdout unless an output file is given
+		outputFile = "-"
 	} else {
 		// Auto-generate output filename
 		ext := filepath.Ext(inputFile)
 		base := strings.TrimSuffix(inputFile, ext)
 		if format == "pdf" {
 			outputFile = base + ".pdf"
+		} else if format == "image" {
+			outputFile = base + "." + buildImageOptions().Extension()
 		} else {
 			outputFile = base + ".html"
 		}
 	}
 
-	// Validate input file
-	if !utils.FileExists(inputFile) {
-		fmt.Printf("Error: Input file '%s' does not exist\n", inputFile)
+	// Status messages go to stderr when stdout carries the document
+	console := io.Writer(os.Stdout)
+	if outputFile == "-" || preview {
+		console = os.Stderr
+	}
+	fail := func(format string, args ...interface{}) {
+		fmt.Fprintf(console, format, args...)
 		os.Exit(1)
 	}
 
-	// Read markdown file
-	content, err := utils.ReadFile(inputFile)
-	if err != nil {
-		fmt.Printf("Error reading file: %v\n", err)
-		os.Exit(1)
+	// "-" reads markdown from stdin
+	input := io.Reader(os.Stdin)
+	if inputFile != "-" {
+		// Validate input file
+		if !utils.FileExists(inputFile) {
+			fail("Error: Input file '%s' does not exist\n", inputFile)
+		}
+		file, err := os.Open(inputFile)
+		if err != nil {
+			fail("Error reading file: %v\n", err)
+		}
+		defer file.Close()
+		input = file
 	}
 
 	// Check if wkhtmltopdf is installed for PDF conversion
-	if format == "pdf" && !converter.IsWkhtmltopdfInstalled() {
-		fmt.Println("Error: wkhtmltopdf is not installed")
-		fmt.Println(converter.GetWkhtmltopdfInstallInstructions())
-		os.Exit(1)
+	if format == "pdf" && pdfEngine == mdconvert.EngineWkhtmltopdf && !mdconvert.IsWkhtmltopdfInstalled() {
+		fail("Error: wkhtmltopdf is not installed\n%s\n", mdconvert.InstallInstructions())
+	}
+
+	// Images are rendered by wkhtmltoimage, which ships with wkhtmltopdf
+	if format == "image" && !mdconvert.IsWkhtmltoimageInstalled() {
+		fail("Error: wkhtmltoimage is not installed\n%s\n", mdconvert.InstallInstructions())
+	}
+
+	inputDir := "."
+	if inputFile != "-" {
+		inputDir = filepath.Dir(inputFile)
+	}
+	opts, err := buildOptions(inputDir)
+	if err != nil {
+		fail("Error: %v\n", err)
 	}
 
+	// Stop the conversion, including any external renderer, on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	// Convert based on format
 	switch format {
 	case "pdf":
 		// Convert markdown to PDF
-		err = converter.ConvertMarkdownFileToPDF(inputFile, outputFile, theme)
+		renderer, err := mdconvert.NewPDFRenderer(pdfEngine)
 		if err != nil {
-			fmt.Printf("Error converting to PDF: %v\n", err)
-			os.Exit(1)
+			fail("Error: %v\n", err)
 		}
-		fmt.Printf("Successfully converted '%s' to '%s'\n", inputFile, outputFile)
-		
-	case "html":
-		// Convert markdown to HTML
-		html, err := converter.ConvertToHTML(content, theme)
+		err = writeOutput(outputFile, func(w io.Writer) error {
+			return mdconvert.ConvertPDF(ctx, renderer, input, w, opts)
+		})
 		if err != nil {
-			fmt.Printf("Error converting markdown: %v\n", err)
-			os.Exit(1)
+			fail("Error converting to PDF: %v\n", err)
 		}
 
+	case "html":
 		// Show preview if requested
 		if preview {
 			fmt.Println("=== HTML Preview ===")
-			fmt.Println(html)
+			if err := mdconvert.ConvertHTML(ctx, input, os.Stdout, opts); err != nil {
+				fail("Error converting markdown: %v\n", err)
+			}
+			fmt.Println()
 			fmt.Println("=== End Preview ===")
 			return
 		}
 
-		// Write output file
-		err = utils.WriteFile(outputFile, html)
+		// Convert markdown to HTML
+		err = writeOutput(outputFile, func(w io.Writer) error {
+			return mdconvert.ConvertHTML(ctx, input, w, opts)
+		})
 		if err != nil {
-			fmt.Printf("Error writing output file: %v\n", err)
-			os.Exit(1)
+			fail("Error converting markdown: %v\n", err)
+		}
+
+	case "image":
+		// Render the HTML output to PNG or JPEG
+		err = writeOutput(outputFile, func(w io.Writer) error {
+			return mdconvert.ConvertImage(ctx, input, w, opts, buildImageOptions())
+		})
+		if err != nil {
+			fail("Error converting to image: %v\n", err)
 		}
 
-		fmt.Printf("Successfully converted '%s' to '%s'\n", inputFile, outputFile)
-		
 	default:
-		fmt.Printf("Error: Unsupported format '%s'. Supported formats: html, pdf\n", format)
-		os.Exit(1)
+		fail("Error: Unsupported format '%s'. Supported formats: html, pdf, image\n", format)
+	}
+
+	if outputFile != "-" {
+		fmt.Fprintf(console, "Successfully converted '%s' to '%s'\n", inputFile, outputFile)
+	}
+}
+
+// writeOutput streams a conversion to the output file, or to stdout for
+// "-". A failed conversion never leaves a partial file behind.
+func writeOutput(path string, convert func(w io.Writer) error) error {
+	if path == "-" {
+		out := bufio.NewWriter(os.Stdout)
+		if err := convert(out); err != nil {
+			return err
+		}
+		return out.Flush()
+	}
+
+	if err := utils.EnsureDirectoryExists(filepath.Dir(path)); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	out := bufio.NewWriter(file)
+	err = convert(out)
+	if err == nil {
+		err = out.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// addConversionFlags registers the flags shared by every command that converts markdown
+func addConversionFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringVarP(&theme, "theme", "t", mdconvert.ThemeLight, "Theme: light or dark")
+	flags.StringVar(&pdfEngine, "pdf-engine", mdconvert.EngineWkhtmltopdf, "PDF engine: wkhtmltopdf or native")
+	flags.StringVar(&fontDir, "font-dir", "", "Directory with custom font files (.ttf, .otf, .woff, .woff2)")
+	flags.StringVar(&fontBody, "font-body", "", "Font family for body text")
+	flags.StringVar(&fontHeadings, "font-headings", "", "Font family for headings (default: body font)")
+	flags.StringVar(&fontCode, "font-code", "", "Font family for code")
+	flags.BoolVar(&embedFonts, "embed-fonts", true, "Embed font files in HTML output instead of linking them")
+	flags.BoolVar(&copyButtons, "copy-buttons", false, "Add a copy button to every code block in HTML output")
+	flags.StringVar(&breakBefore, "break-before", "", "Start a new PDF page before these heading levels, e.g. h1,h2")
+	flags.StringArrayVar(&filters, "filter", nil, "Rewrite the document AST as JSON with this command before rendering (repeatable)")
+	flags.StringArrayVar(&diagrams, "diagram", nil, "Render fences of a language to SVG with a command, e.g. dot=\"dot -Tsvg\"; an empty command disables the language (repeatable)")
+	flags.StringVar(&diagramCache, "diagram-cache", "", "Directory for cached diagram SVGs (default: user cache directory)")
+	flags.StringVar(&extensions, "ext", "", "Enable markdown extensions, e.g. footnote,deflist (available: "+strings.Join(mdconvert.ExtensionNames(), ", ")+")")
+	flags.StringVar(&includeRoot, "include-root", "", "Directory included files must stay inside (default: directory of the project config, else of the input)")
+	flags.StringVar(&configFile, "config", "", "Project config file (default: nearest "+mdconvert.ConfigFileName+" from the input directory up)")
+	flags.StringVar(&watermarkText, "watermark", "", "Stamp every page with this text, e.g. DRAFT")
+	flags.StringVar(&watermarkImage, "watermark-image", "", "Stamp every page with this PNG or JPEG image")
+	flags.Float64Var(&watermarkOpacity, "watermark-opacity", 0.15, "Watermark opacity between 0 and 1")
+	flags.Float64Var(&watermarkRotation, "watermark-rotation", mdconvert.DefaultWatermarkRotation, "Watermark rotation in degrees")
+	flags.StringVar(&watermarkPosition, "watermark-position", mdconvert.WatermarkCenter, "Watermark position: center, top or bottom")
+	flags.DurationVar(&timeout, "timeout", 0, "Give up on a document after this long, e.g. 30s (default: no limit)")
+	flags.StringVar(&docTitle, "doc-title", "", "Document title metadata (default: front matter title or first heading)")
+	flags.StringVar(&docAuthor, "author", "", "Document author metadata (default: front matter author)")
+	flags.StringVar(&docSubject, "subject", "", "Document subject metadata (default: front matter subject)")
+	flags.StringVar(&docKeywords, "keywords", "", "Comma separated keywords metadata (default: front matter keywords)")
+	flags.StringVar(&docLanguage, "lang", "", "Document language such as tr or de, used for quotes and metadata (default: front matter lang)")
+}
+
+// buildOptions collects conversion options from the command line flags and
+// the project config file found from inputDir
+func buildOptions(inputDir string) (mdconvert.Options, error) {
+	opts := mdconvert.Options{Theme: theme, Timeout: timeout, CopyButtons: copyButtons}
+
+	levels, err := mdconvert.ParseHeadingLevels(breakBefore)
+	if err != nil {
+		return opts, fmt.Errorf("--break-before: %w", err)
+	}
+	opts.BreakBefore = levels
+
+	names, err := mdconvert.ParseExtensions(extensions)
+	if err != nil {
+		return opts, fmt.Errorf("--ext: %w", err)
+	}
+	opts.Extensions = names
+	opts.Filters = filters
+
+	path := configFile
+	if path == "" {
+		path = mdconvert.FindConfig(inputDir)
+	}
+	opts.Includes = &mdconvert.Includes{Root: includeRoot, Dir: inputDir}
+	if includeRoot == "" {
+		opts.Includes.Root = inputDir
+		if path != "" {
+			opts.Includes.Root = filepath.Dir(path)
+		}
+	}
+	if path != "" {
+		cfg, err := mdconvert.LoadConfig(path)
+		if err != nil {
+			return opts, err
+		}
+		for _, name := range cfg.Extensions {
+			if !containsFold(opts.Extensions, name) {
+				opts.Extensions = append(opts.Extensions, name)
+			}
+		}
+		opts.References = cfg.References
+	}
+
+	opts.Diagrams = mdconvert.DefaultDiagrams()
+	if len(diagrams) > 0 {
+		for _, diagram := range diagrams {
+			language, command, ok := strings.Cut(diagram, "=")
+			if !ok || strings.TrimSpace(language) == "" {
+				return opts, fmt.Errorf("--diagram: expected language=command, got %q", diagram)
+			}
+			opts.Diagrams[strings.TrimSpace(language)] = strings.TrimSpace(command)
+		}
+	}
+	opts.DiagramCache = diagramCache
+
+	if fontDir != "" || fontBody != "" || fontHeadings != "" || fontCode != "" {
+		opts.Fonts = &mdconvert.FontConfig{
+			Dir:      fontDir,
+			Body:     fontBody,
+			Headings: fontHeadings,
+			Code:     fontCode,
+			Embed:    embedFonts,
+		}
+	}
+
+	if watermarkText != "" || watermarkImage != "" {
+		switch watermarkPosition {
+		case mdconvert.WatermarkCenter, mdconvert.WatermarkTop, mdconvert.WatermarkBottom:
+		default:
+			return opts, fmt.Errorf("--watermark-position: unsupported position %q", watermarkPosition)
+		}
+		opts.Watermark = &mdconvert.Watermark{
+			Text:     watermarkText,
+			Image:    watermarkImage,
+			Opacity:  watermarkOpacity,
+			Rotation: watermarkRotation,
+			Position: watermarkPosition,
+		}
+	}
+
+	if docTitle != "" || docAuthor != "" || docSubject != "" || docKeywords != "" || docLanguage != "" {
+		opts.Metadata = &mdconvert.Metadata{
+			Title:    docTitle,
+			Author:   docAuthor,
+			Subject:  docSubject,
+			Language: docLanguage,
+		}
+		for _, keyword := range strings.Split(docKeywords, ",") {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				opts.Metadata.Keywords = append(opts.Metadata.Keywords, keyword)
+			}
+		}
+	}
+
+	return opts, nil
+}
+
+// containsFold reports whether names contains name, ignoring case
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildImageOptions collects image output options from the command line flags
+func buildImageOptions() mdconvert.ImageOptions {
+	imgFormat := imageFormat
+	if imgFormat == "" && outputFile != "" {
+		imgFormat = strings.TrimPrefix(strings.ToLower(filepath.Ext(outputFile)), ".")
+	}
+
+	return mdconvert.ImageOptions{
+		Format:     imgFormat,
+		Width:      imageWidth,
+		Quality:    imageQuality,
+		CropX:      cropX,
+		CropY:      cropY,
+		CropWidth:  cropWidth,
+		CropHeight: cropHeight,
+		FirstPage:  firstPage,
 	}
 }
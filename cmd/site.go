@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"markdown-to-html/pkg/mdconvert"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	siteOutput         string
+	siteBacklinksTitle string
+	siteStrict         bool
+)
+
+func newSiteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "site [dir]",
+		Short: "Convert a directory of markdown pages into linked HTML pages",
+		Long: `Converts every markdown file under a directory to HTML, keeping the
+directory layout. [[Page Name]] and [[Page Name|label]] links resolve to the
+page with that title or file name, [[Page#Heading]] links to a heading, and
+every page gets a backlinks section listing the pages that link to it.
+
+Links that match no page or several pages are reported and left as text.
+
+Examples:
+  markdown-converter site docs -o public
+  markdown-converter site wiki -o out --backlinks-title "Linked from"
+  markdown-converter site docs --strict                 # Fails on broken links`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  runSite,
+	}
+
+	cmd.Flags().StringVarP(&siteOutput, "output", "o", "site", "Output directory")
+	cmd.Flags().StringVar(&siteBacklinksTitle, "backlinks-title", mdconvert.DefaultBacklinksTitle, "Heading of the backlinks section")
+	cmd.Flags().BoolVar(&siteStrict, "strict", false, "Fail when a wiki link is unresolved or ambiguous")
+	addConversionFlags(cmd)
+
+	return cmd
+}
+
+func runSite(cmd *cobra.Command, args []string) {
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+
+	site, err := mdconvert.LoadSite(dir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	site.BacklinksTitle = siteBacklinksTitle
+
+	opts, err := buildOptions(dir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Stop the conversion, including any external filter, on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	issues, err := mdconvert.ConvertSite(ctx, site, siteOutput, opts, func(inputFile, outputFile string) {
+		fmt.Printf("Converted %s to %s\n", inputFile, outputFile)
+	})
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", issue)
+	}
+	if err != nil {
+		fmt.Printf("Error creating site: %v\n", err)
+		os.Exit(1)
+	}
+	if siteStrict && len(issues) > 0 {
+		fmt.Printf("Error: %d wiki links did not resolve\n", len(issues))
+		os.Exit(1)
+	}
+
+	fmt.Printf("Successfully converted %d pages into '%s'\n", len(site.Pages), siteOutput)
+}
@@ -6,25 +6,76 @@ import (
 	"html/template"
 	"log"
 	"net/http"
-	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
-	"markdown-to-html/internal/converter"
 	"markdown-to-html/internal/utils"
+	"markdown-to-html/pkg/mdconvert"
 )
 
 type ConversionRequest struct {
-	Markdown string `json:"markdown"`
-	Theme    string `json:"theme"`
-	Format   string `json:"format"`
+	Markdown  string `json:"markdown"`
+	Theme     string `json:"theme"`
+	Format    string `json:"format"`
+	Engine    string `json:"engine,omitempty"`
+	Watermark string `json:"watermark,omitempty"`
+
+	// Image output (format "image")
+	ImageFormat string `json:"imageFormat,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Quality     int    `json:"quality,omitempty"`
+	FirstPage   bool   `json:"firstPage,omitempty"`
 }
 
+// conversionTimeout bounds the time spent on a single request's document
+const conversionTimeout = 2 * time.Minute
+
+// maxRenderBody limits the raw markdown accepted by /render
+const maxRenderBody = 10 << 20
+
 type ConversionResponse struct {
 	Success bool   `json:"success"`
 	HTML    string `json:"html,omitempty"`
 	Error   string `json:"error,omitempty"`
 }
 
+// options converts the request into conversion options
+func (req ConversionRequest) options() mdconvert.Options {
+	opts := mdconvert.Options{Theme: req.Theme, Timeout: conversionTimeout}
+	if req.Watermark != "" {
+		opts.Watermark = &mdconvert.Watermark{
+			Text:     req.Watermark,
+			Rotation: mdconvert.DefaultWatermarkRotation,
+		}
+	}
+	return opts
+}
+
+// converters holds one shared converter per built-in theme; parallel
+// requests reuse it instead of rebuilding the markdown parser and page
+// template every time
+var converters sync.Map
+
+// converter returns a converter for the request's options
+func (req ConversionRequest) converter() (*mdconvert.Converter, error) {
+	// Watermarks and unknown themes are not cached, so clients cannot grow
+	// the cache without bound
+	cacheable := req.Watermark == "" && (req.Theme == "" || req.Theme == mdconvert.ThemeLight || req.Theme == mdconvert.ThemeDark)
+	if cacheable {
+		if c, ok := converters.Load(req.Theme); ok {
+			return c.(*mdconvert.Converter), nil
+		}
+	}
+
+	c, err := mdconvert.NewConverter(req.options())
+	if err != nil || !cacheable {
+		return c, err
+	}
+	shared, _ := converters.LoadOrStore(req.Theme, c)
+	return shared.(*mdconvert.Converter), nil
+}
+
 func main() {
 	// Serve static files
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("web/static"))))
@@ -34,6 +85,7 @@ func main() {
 	http.HandleFunc("/convert", handleConvert)
 	http.HandleFunc("/upload", handleUpload)
 	http.HandleFunc("/download", handleDownload)
+	http.HandleFunc("/render", handleRender)
 	
 	port := ":8080"
 	fmt.Printf("🚀 Web arayüzü başlatılıyor... http://localhost%s\n", port)
@@ -79,7 +131,7 @@ func handleConvert(w http.ResponseWriter, r *http.Request) {
 	
 	// Set default theme
 	if req.Theme == "" {
-		req.Theme = "light"
+		req.Theme = mdconvert.ThemeLight
 	}
 	
 	// Set default format
@@ -91,7 +143,11 @@ func handleConvert(w http.ResponseWriter, r *http.Request) {
 	
 	if req.Format == "html" {
 		// Convert to HTML
-		html, err := converter.ConvertToHTML(req.Markdown, req.Theme)
+		c, err := req.converter()
+		var html string
+		if err == nil {
+			html, err = c.ConvertToHTMLContext(r.Context(), req.Markdown)
+		}
 		if err != nil {
 			response = ConversionResponse{
 				Success: false,
@@ -175,29 +231,110 @@ func handleDownload(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	if req.Format == "pdf" {
-		// Generate PDF
-		if !converter.IsWkhtmltopdfInstalled() {
+		// Pick the PDF engine; fall back to the native renderer when
+		// wkhtmltopdf is missing and no engine was requested
+		if req.Engine == "" && !mdconvert.IsWkhtmltopdfInstalled() {
+			req.Engine = mdconvert.EngineNative
+		}
+		renderer, err := mdconvert.NewPDFRenderer(req.Engine)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Engine != mdconvert.EngineNative && !mdconvert.IsWkhtmltopdfInstalled() {
 			http.Error(w, "wkhtmltopdf yüklü değil", http.StatusInternalServerError)
 			return
 		}
 		
-		// Create temporary file
-		tempFile := filepath.Join("output", "temp_download.pdf")
-		
-		err := converter.ConvertToPDF(req.Markdown, tempFile, req.Theme)
+		// Stream PDF directly to the response; nothing is written on failure,
+		// so the headers can still be replaced by an error response
+		w.Header().Set("Content-Disposition", "attachment; filename=converted.pdf")
+		w.Header().Set("Content-Type", "application/pdf")
+
+		err = mdconvert.RenderPDF(r.Context(), renderer, w, req.Markdown, req.options())
 		if err != nil {
+			w.Header().Del("Content-Disposition")
 			http.Error(w, "PDF generation error: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		
-		// Serve file for download
-		w.Header().Set("Content-Disposition", "attachment; filename=converted.pdf")
-		w.Header().Set("Content-Type", "application/pdf")
-		http.ServeFile(w, r, tempFile)
-		
-		// Clean up temp file
-		// Note: In production, you might want to use a proper temp file system
+	} else if req.Format == "image" {
+		if !mdconvert.IsWkhtmltoimageInstalled() {
+			http.Error(w, "wkhtmltoimage yüklü değil", http.StatusInternalServerError)
+			return
+		}
+
+		img := mdconvert.ImageOptions{
+			Format:    req.ImageFormat,
+			Width:     req.Width,
+			Quality:   req.Quality,
+			FirstPage: req.FirstPage,
+		}
+		w.Header().Set("Content-Disposition", "attachment; filename=converted."+img.Extension())
+		w.Header().Set("Content-Type", img.ContentType())
+
+		err := mdconvert.RenderImage(r.Context(), w, req.Markdown, req.options(), img)
+		if err != nil {
+			w.Header().Del("Content-Disposition")
+			http.Error(w, "Image generation error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 	} else {
 		http.Error(w, "Unsupported format for download", http.StatusBadRequest)
 	}
 }
+
+// handleRender streams a raw markdown request body straight into the
+// response. The format, theme and engine come from the query string, e.g.
+// curl --data-binary @doc.md 'localhost:8080/render?format=pdf' > doc.pdf
+func handleRender(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	req := ConversionRequest{
+		Theme:     query.Get("theme"),
+		Format:    query.Get("format"),
+		Engine:    query.Get("engine"),
+		Watermark: query.Get("watermark"),
+	}
+	body := http.MaxBytesReader(w, r.Body, maxRenderBody)
+
+	switch req.Format {
+	case "", "html":
+		// HTML is written while it renders, so errors after the first
+		// write can only end the response early
+		c, err := req.converter()
+		if err != nil {
+			http.Error(w, "Conversion error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := c.ConvertHTML(r.Context(), body, w); err != nil {
+			http.Error(w, "Conversion error: "+err.Error(), http.StatusInternalServerError)
+		}
+
+	case "pdf":
+		if req.Engine == "" && !mdconvert.IsWkhtmltopdfInstalled() {
+			req.Engine = mdconvert.EngineNative
+		}
+		renderer, err := mdconvert.NewPDFRenderer(req.Engine)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Engine != mdconvert.EngineNative && !mdconvert.IsWkhtmltopdfInstalled() {
+			http.Error(w, "wkhtmltopdf yüklü değil", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		if err := mdconvert.ConvertPDF(r.Context(), renderer, body, w, req.options()); err != nil {
+			http.Error(w, "PDF generation error: "+err.Error(), http.StatusInternalServerError)
+		}
+
+	default:
+		http.Error(w, "Unsupported format for render", http.StatusBadRequest)
+	}
+}
@@ -4,11 +4,15 @@ go 1.25
 
 require (
 	github.com/SebastiaanKlippert/go-wkhtmltopdf v1.9.2
+	github.com/go-pdf/fpdf v0.9.0
 	github.com/spf13/cobra v1.8.0
 	github.com/yuin/goldmark v1.7.0
+	github.com/yuin/goldmark-meta v1.1.0
+	gopkg.in/yaml.v2 v2.3.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 )
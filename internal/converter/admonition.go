@@ -0,0 +1,421 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindAdmonition is the node kind of alert callouts and ::: containers
+var KindAdmonition = ast.NewNodeKind("Admonition")
+
+// Admonition is a callout box: a GitHub alert such as > [!NOTE] or a
+// :::warning container
+type Admonition struct {
+	ast.BaseBlock
+
+	// AdmonitionType is the lower-case type as written, e.g. "note"
+	AdmonitionType string
+
+	// Title replaces the default title of the type when not empty
+	Title string
+
+	// Collapsible admonitions render as <details>, closed unless Open
+	Collapsible bool
+	Open        bool
+
+	fence  int  // Number of colons of a ::: container, 0 for alerts
+	closed bool // The closing fence has been read
+}
+
+// Kind implements ast.Node
+func (n *Admonition) Kind() ast.NodeKind {
+	return KindAdmonition
+}
+
+// Dump implements ast.Node
+func (n *Admonition) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"AdmonitionType": n.AdmonitionType,
+		"Title":          n.Title,
+	}, nil)
+}
+
+// admonitionStyle is the look shared by related admonition types
+type admonitionStyle struct {
+	name  string // CSS class suffix
+	alert string // Bootstrap alert variant
+	icon  string // SVG body of a 16x16 icon
+	light [3]int // Accent colour on light pages
+	dark  [3]int // Accent colour on dark pages
+}
+
+var (
+	styleNote = admonitionStyle{"note", "primary",
+		`<circle cx="8" cy="8" r="6.75"/><path d="M8 7.25v4"/><circle cx="8" cy="4.75" r="0.5" fill="currentColor"/>`,
+		[3]int{9, 105, 218}, [3]int{68, 147, 248}}
+	styleTip = admonitionStyle{"tip", "success",
+		`<path d="M8 1.5a4.5 4.5 0 0 0-2.5 8.25V11.5h5V9.75A4.5 4.5 0 0 0 8 1.5z"/><path d="M6 14h4"/>`,
+		[3]int{26, 127, 55}, [3]int{63, 185, 80}}
+	styleImportant = admonitionStyle{"important", "secondary",
+		`<path d="M2.5 2h11a1 1 0 0 1 1 1v7.5a1 1 0 0 1-1 1H8l-3.5 2.75V11.5h-2a1 1 0 0 1-1-1V3a1 1 0 0 1 1-1z"/><path d="M8 4.5v3"/><circle cx="8" cy="9.25" r="0.5" fill="currentColor"/>`,
+		[3]int{130, 80, 223}, [3]int{171, 125, 248}}
+	styleWarning = admonitionStyle{"warning", "warning",
+		`<path d="M8 1.75l6.5 12H1.5z"/><path d="M8 6v3.5"/><circle cx="8" cy="11.75" r="0.5" fill="currentColor"/>`,
+		[3]int{154, 103, 0}, [3]int{210, 153, 34}}
+	styleCaution = admonitionStyle{"caution", "danger",
+		`<path d="M5.2 1.5h5.6l3.7 3.7v5.6l-3.7 3.7H5.2l-3.7-3.7V5.2z"/><path d="M8 4.75v3.75"/><circle cx="8" cy="11" r="0.5" fill="currentColor"/>`,
+		[3]int{207, 34, 46}, [3]int{248, 81, 73}}
+)
+
+// admonitionStyles lists every style in a stable order for the CSS
+var admonitionStyles = []admonitionStyle{styleNote, styleTip, styleImportant, styleWarning, styleCaution}
+
+// admonitionTypes maps the GitHub alert types and common admonition names
+// to their default title and style
+var admonitionTypes = map[string]struct {
+	title string
+	style admonitionStyle
+}{
+	"note":      {"Note", styleNote},
+	"info":      {"Info", styleNote},
+	"abstract":  {"Abstract", styleNote},
+	"summary":   {"Summary", styleNote},
+	"todo":      {"Todo", styleNote},
+	"example":   {"Example", styleNote},
+	"quote":     {"Quote", styleNote},
+	"tip":       {"Tip", styleTip},
+	"hint":      {"Hint", styleTip},
+	"success":   {"Success", styleTip},
+	"check":     {"Check", styleTip},
+	"important": {"Important", styleImportant},
+	"question":  {"Question", styleImportant},
+	"faq":       {"FAQ", styleImportant},
+	"warning":   {"Warning", styleWarning},
+	"attention": {"Attention", styleWarning},
+	"caution":   {"Caution", styleCaution},
+	"danger":    {"Danger", styleCaution},
+	"error":     {"Error", styleCaution},
+	"bug":       {"Bug", styleCaution},
+	"failure":   {"Failure", styleCaution},
+}
+
+// admonitionTitle returns the title and style of an admonition; unknown
+// types look like notes titled after the type
+func admonitionTitle(n *Admonition) (string, admonitionStyle) {
+	known, ok := admonitionTypes[n.AdmonitionType]
+	title := n.Title
+	if title == "" {
+		title = known.title
+		if !ok {
+			title = strings.ToUpper(n.AdmonitionType[:1]) + n.AdmonitionType[1:]
+		}
+	}
+	if !ok {
+		return title, styleNote
+	}
+	return title, known.style
+}
+
+// Markers match the type, fold marker and title of a [!TYPE] line and of
+// the text after a ::: fence
+var (
+	alertMarker     = regexp.MustCompile(`^\[!([A-Za-z]+)\]([+-]?)(?:[ \t]+(.*))?$`)
+	containerMarker = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_]*)([+-]?)(?:[ \t]+(.*))?$`)
+)
+
+// newAdmonition builds an admonition from a marker match
+func newAdmonition(match [][]byte) *Admonition {
+	return &Admonition{
+		AdmonitionType: strings.ToLower(string(match[1])),
+		Title:          strings.TrimSpace(string(match[3])),
+		Collapsible:    len(match[2]) > 0,
+		Open:           string(match[2]) == "+",
+	}
+}
+
+// alerts turns blockquotes starting with a [!TYPE] line of a known type
+// into admonitions
+type alerts struct{}
+
+// Transform implements parser.ASTTransformer
+func (t *alerts) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var quotes []*ast.Blockquote
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if quote, ok := n.(*ast.Blockquote); ok && entering {
+			quotes = append(quotes, quote)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, quote := range quotes {
+		para, ok := quote.FirstChild().(*ast.Paragraph)
+		if !ok || para.Lines().Len() == 0 {
+			continue
+		}
+		first := para.Lines().At(0)
+		match := alertMarker.FindSubmatch(util.TrimRightSpace(first.Value(source)))
+		if match == nil {
+			continue
+		}
+		if _, ok := admonitionTypes[strings.ToLower(string(match[1]))]; !ok {
+			continue
+		}
+
+		// Drop the marker line from the paragraph
+		for child := para.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			if start := inlineStart(child); start < 0 || start >= first.Stop {
+				break
+			}
+			para.RemoveChild(para, child)
+			child = next
+		}
+		lines := para.Lines()
+		lines.SetSliced(1, lines.Len())
+		if !para.HasChildren() {
+			quote.RemoveChild(quote, para)
+		} else if text, ok := para.FirstChild().(*ast.Text); ok {
+			// The next line may be indented after the quote marker
+			text.Segment = text.Segment.TrimLeftSpace(source)
+		}
+
+		admonition := newAdmonition(match)
+		for child := quote.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			admonition.AppendChild(admonition, child)
+			child = next
+		}
+		quote.Parent().ReplaceChild(quote.Parent(), quote, admonition)
+	}
+}
+
+// inlineStart returns the source offset where an inline node begins, or -1
+// when it has no text of its own
+func inlineStart(n ast.Node) int {
+	start := -1
+	_ = ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if text, ok := child.(*ast.Text); ok && entering {
+			start = text.Segment.Start
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return start
+}
+
+// containerParser parses :::type Title ... ::: containers. A fence of more
+// colons can enclose containers with shorter fences.
+type containerParser struct{}
+
+// Trigger implements parser.BlockParser
+func (p *containerParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+// Open implements parser.BlockParser
+func (p *containerParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+	fence := fenceLength(line[pos:])
+	if fence < 3 {
+		return nil, parser.NoChildren
+	}
+	match := containerMarker.FindSubmatch(bytes.TrimSpace(line[pos+fence:]))
+	if match == nil {
+		return nil, parser.NoChildren
+	}
+
+	node := newAdmonition(match)
+	node.fence = fence
+	reader.Advance(segment.Len() - 1)
+	return node, parser.HasChildren
+}
+
+// Continue implements parser.BlockParser
+func (p *containerParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	n := node.(*Admonition)
+	trimmed := bytes.TrimSpace(line)
+	if fence := fenceLength(trimmed); fence >= n.fence && fence == len(trimmed) && !hasOpenContainer(n) {
+		n.closed = true
+		reader.Advance(segment.Len() - 1)
+		return parser.Close
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+// Close implements parser.BlockParser
+func (p *containerParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+// CanInterruptParagraph implements parser.BlockParser
+func (p *containerParser) CanInterruptParagraph() bool {
+	return true
+}
+
+// CanAcceptIndentedLine implements parser.BlockParser
+func (p *containerParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// fenceLength counts the colons at the start of line
+func fenceLength(line []byte) int {
+	n := 0
+	for n < len(line) && line[n] == ':' {
+		n++
+	}
+	return n
+}
+
+// hasOpenContainer reports whether a nested container inside n is still
+// waiting for its closing fence
+func hasOpenContainer(n ast.Node) bool {
+	for child := n.LastChild(); child != nil; child = child.LastChild() {
+		if inner, ok := child.(*Admonition); ok && inner.fence > 0 && !inner.closed {
+			return true
+		}
+	}
+	return false
+}
+
+// admonitionHTMLRenderer renders admonitions as Bootstrap alerts
+type admonitionHTMLRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *admonitionHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindAdmonition, r.render)
+}
+
+func (r *admonitionHTMLRenderer) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Admonition)
+	tag, titleTag := "div", "p"
+	if n.Collapsible {
+		tag, titleTag = "details", "summary"
+	}
+	if !entering {
+		_, _ = w.WriteString("</" + tag + ">\n")
+		return ast.WalkContinue, nil
+	}
+
+	title, style := admonitionTitle(n)
+	_, _ = w.WriteString("<" + tag + ` class="alert alert-` + style.alert + " admonition admonition-" + style.name + `"`)
+	if n.Collapsible && n.Open {
+		_, _ = w.WriteString(` open="open"`)
+	}
+	if !n.Collapsible {
+		_, _ = w.WriteString(` role="note"`)
+	}
+	_, _ = w.WriteString(">\n<" + titleTag + ` class="admonition-title">`)
+	_, _ = w.WriteString(`<svg class="admonition-icon" viewBox="0 0 16 16" width="16" height="16" fill="none" stroke="currentColor" stroke-width="1.5" stroke-linecap="round" stroke-linejoin="round" aria-hidden="true">`)
+	_, _ = w.WriteString(style.icon + "</svg>")
+	_, _ = w.WriteString(html.EscapeString(title) + "</" + titleTag + ">\n")
+	return ast.WalkContinue, nil
+}
+
+// admonitionExtension adds GitHub alerts and ::: containers
+type admonitionExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *admonitionExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&containerParser{}, 150)),
+		parser.WithASTTransformers(util.Prioritized(&alerts{}, 200)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&admonitionHTMLRenderer{}, 500),
+	))
+}
+
+// expandAdmonitions opens every collapsible admonition, for output that
+// cannot be clicked
+func expandAdmonitions(root ast.Node) {
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if admonition, ok := n.(*Admonition); ok && entering {
+			admonition.Open = true
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// admonitionCSS styles admonitions with accent colours for theme
+func admonitionCSS(theme string) string {
+	var sb strings.Builder
+	sb.WriteString(`
+		.markdown-content .admonition {
+			border-left-width: 0.25rem;
+			padding: 0.75rem 1rem;
+		}
+		.markdown-content .admonition > :last-child {
+			margin-bottom: 0;
+		}
+		.markdown-content .admonition-title {
+			font-weight: 600;
+			margin-bottom: 0.5rem;
+		}
+		.markdown-content .admonition-icon {
+			width: 1em;
+			height: 1em;
+			margin-right: 0.5rem;
+			vertical-align: -0.125em;
+		}
+		.markdown-content details.admonition > summary {
+			cursor: pointer;
+		}
+		.markdown-content details.admonition:not([open]) > summary {
+			margin-bottom: 0;
+		}`)
+	if theme == ThemeDark {
+		sb.WriteString(`
+		.markdown-content .admonition {
+			background-color: #2b3035;
+			border-color: #495057;
+			color: #e9ecef;
+		}
+		.markdown-content .admonition p,
+		.markdown-content .admonition li {
+			color: #e9ecef;
+		}`)
+	} else {
+		// Bootstrap has no purple alert
+		sb.WriteString(`
+		.markdown-content .admonition-important {
+			background-color: #f3eefc;
+			border-color: #d8c8f5;
+			color: #3d2470;
+		}`)
+	}
+
+	for _, style := range admonitionStyles {
+		accent := style.light
+		if theme == ThemeDark {
+			accent = style.dark
+		}
+		color := cssColor(accent)
+		sb.WriteString(`
+		.markdown-content .admonition-` + style.name + ` {
+			border-left-color: ` + color + `;
+		}
+		.markdown-content .admonition-` + style.name + ` > .admonition-title {
+			color: ` + color + `;
+		}`)
+	}
+	return sb.String()
+}
+
+// cssColor formats an RGB colour as #rrggbb
+func cssColor(c [3]int) string {
+	return fmt.Sprintf("#%02x%02x%02x", c[0], c[1], c[2])
+}
@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchmarkSource is the sample document every benchmark converts
+func benchmarkSource(b *testing.B) string {
+	b.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "..", "examples", "sample.md"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return string(data)
+}
+
+// benchmarkBatch is the number of files in a batch
+const benchmarkBatch = 20
+
+// webOptions mirror the options the web server converts requests with
+var webOptions = Options{Timeout: 2 * time.Minute}
+
+// BenchmarkConvertFiles measures CLI batch conversion, where one Converter
+// serves every file
+func BenchmarkConvertFiles(b *testing.B) {
+	source := benchmarkSource(b)
+	dir := b.TempDir()
+	inputs := make([]string, benchmarkBatch)
+	for i := range inputs {
+		inputs[i] = filepath.Join(dir, fmt.Sprintf("doc%d.md", i))
+		if err := os.WriteFile(inputs[i], []byte(source), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	output := filepath.Join(dir, "out")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := ConvertFiles(context.Background(), inputs, output, Options{}, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*benchmarkBatch)/b.Elapsed().Seconds(), "docs/s")
+}
+
+// BenchmarkConverterBatch converts a batch in memory with a shared
+// Converter
+func BenchmarkConverterBatch(b *testing.B) {
+	source := benchmarkSource(b)
+	c, err := NewConverter(Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < benchmarkBatch; j++ {
+			if _, err := c.ConvertToHTML(source); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N*benchmarkBatch)/b.Elapsed().Seconds(), "docs/s")
+}
+
+// BenchmarkConvertToHTMLBatch is BenchmarkConverterBatch through the
+// package function, which builds a converter for every document
+func BenchmarkConvertToHTMLBatch(b *testing.B) {
+	source := benchmarkSource(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < benchmarkBatch; j++ {
+			if _, err := ConvertToHTML(source, ThemeLight); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N*benchmarkBatch)/b.Elapsed().Seconds(), "docs/s")
+}
+
+// BenchmarkConverterParallel measures the web server's /render path under
+// parallel load: one shared Converter streaming each request to its
+// response
+func BenchmarkConverterParallel(b *testing.B) {
+	source := benchmarkSource(b)
+	c, err := NewConverter(webOptions)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := c.ConvertHTML(context.Background(), strings.NewReader(source), io.Discard); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "docs/s")
+}
+
+// BenchmarkNewConverterParallel is BenchmarkConverterParallel with a new
+// Converter for every request, as before converters were shared
+func BenchmarkNewConverterParallel(b *testing.B) {
+	source := benchmarkSource(b)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c, err := NewConverter(webOptions)
+			if err == nil {
+				err = c.ConvertHTML(context.Background(), strings.NewReader(source), io.Discard)
+			}
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "docs/s")
+}
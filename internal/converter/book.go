@@ -0,0 +1,489 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/SebastiaanKlippert/go-wkhtmltopdf"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// DefaultTOCTitle is the heading of a book's table of contents
+const DefaultTOCTitle = "Contents"
+
+// Book is an ordered set of markdown chapters rendered as one document
+type Book struct {
+	Title    string   // Shown on the title page; no title page when empty
+	TOCTitle string   // Heading of the table of contents; defaults to DefaultTOCTitle
+	Chapters []string // Paths of the chapter files in reading order
+}
+
+// BookRenderer is implemented by PDF renderers that can typeset a book with
+// a table of contents, bookmarks and continuous page numbers
+type BookRenderer interface {
+	// RenderBook writes the PDF for book to w. Like RenderPDF it writes
+	// nothing on failure.
+	RenderBook(w io.Writer, book *Book, opts Options) error
+}
+
+// ContextBookRenderer is implemented by book renderers that stop work when
+// a context is cancelled
+type ContextBookRenderer interface {
+	// RenderBookContext is RenderBook with cancellation; filters, diagram
+	// commands and external processes are stopped when ctx is done
+	RenderBookContext(ctx context.Context, w io.Writer, book *Book, opts Options) error
+}
+
+func (b *Book) tocTitle() string {
+	if b.TOCTitle != "" {
+		return b.TOCTitle
+	}
+	return DefaultTOCTitle
+}
+
+// LoadSummary reads a SUMMARY.md style manifest: the first level 1 heading
+// becomes the book title and every link to a local .md file becomes a
+// chapter, in order of appearance
+func LoadSummary(path string) (*Book, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read summary: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	book := &Book{}
+	seen := make(map[string]bool)
+
+	root := goldmark.New().Parser().Parse(text.NewReader(source))
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Heading:
+			if n.Level == 1 && book.Title == "" {
+				book.Title = string(n.Text(source))
+			}
+		case *ast.Link:
+			target, _, ok := localMarkdownLink(string(n.Destination))
+			if !ok || target == "" {
+				break
+			}
+			chapter := filepath.Join(dir, target)
+			if !seen[chapter] {
+				seen[chapter] = true
+				book.Chapters = append(book.Chapters, chapter)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	if len(book.Chapters) == 0 {
+		return nil, fmt.Errorf("no chapters found in %s", path)
+	}
+	return book, nil
+}
+
+// localMarkdownLink splits a link to a local markdown file into its file
+// path and fragment. A bare "#fragment" yields an empty path.
+func localMarkdownLink(dest string) (string, string, bool) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || strings.HasPrefix(u.Path, "/") {
+		return "", "", false
+	}
+	if u.Path == "" {
+		return "", u.Fragment, u.Fragment != ""
+	}
+	ext := strings.ToLower(filepath.Ext(u.Path))
+	if ext != ".md" && ext != ".markdown" {
+		return "", "", false
+	}
+	return filepath.FromSlash(u.Path), u.Fragment, true
+}
+
+// frontMatter matches a YAML front matter block at the start of a file
+var frontMatter = regexp.MustCompile(`\A---[ \t]*\r?\n(?s:.*?)\r?\n(?:---|\.\.\.)[ \t]*(?:\r?\n|\z)`)
+
+// splitFrontMatter returns the front matter block and the remaining body
+func splitFrontMatter(source string) (string, string) {
+	loc := frontMatter.FindStringIndex(source)
+	if loc == nil {
+		return "", source
+	}
+	return source[:loc[1]], source[loc[1]:]
+}
+
+// chapterMarker separates chapters in the combined source
+const chapterMarker = "md2pdf-chapter"
+
+var chapterMarkerPattern = regexp.MustCompile(`^<!--\s*` + chapterMarker + `\s+(\d+)\s*-->$`)
+
+// bookDocument parses all chapters as one document. Only the first chapter's
+// front matter is kept, so it controls book-wide settings. Every later
+// chapter starts on a new page, links between chapters point at the
+// matching headings of the combined document and relative image paths are
+// made absolute. Filters from opts then see the whole book in format; they
+// and diagram commands are stopped when ctx is done.
+func bookDocument(ctx context.Context, book *Book, opts Options, format string) (*document, error) {
+	if len(book.Chapters) == 0 {
+		return nil, fmt.Errorf("book has no chapters")
+	}
+
+	// Chapters are expanded one by one, so the combined source has no
+	// directives left to expand
+	chapterOpts := opts
+	chapterOpts.Includes = nil
+
+	var combined strings.Builder
+	chapters := make([]string, len(book.Chapters))
+	standalone := make([][]headingRef, len(book.Chapters))
+	for i, chapter := range book.Chapters {
+		abs, err := filepath.Abs(chapter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve chapter %s: %w", chapter, err)
+		}
+		source, err := os.ReadFile(abs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chapter: %w", err)
+		}
+		// Includes are relative to the chapter that contains them
+		if opts.Includes != nil {
+			inc := *opts.Includes
+			inc.Dir = filepath.Dir(abs)
+			if source, err = expandIncludes(source, &inc); err != nil {
+				return nil, fmt.Errorf("chapter %s: %w", chapter, err)
+			}
+		}
+		front, body := splitFrontMatter(string(source))
+
+		if i == 0 {
+			combined.WriteString(front)
+		} else {
+			fmt.Fprintf(&combined, "\n\n<!-- %s %d -->\n\n", chapterMarker, i)
+		}
+		combined.WriteString(body)
+
+		chapters[i] = abs
+		chapterDoc, err := parseDocument(body, chapterOpts)
+		if err != nil {
+			return nil, err
+		}
+		standalone[i] = headingRefs(chapterDoc)
+	}
+
+	doc, err := parseDocument(combined.String(), chapterOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Walk the combined document, noting which chapter every heading, link
+	// and image belongs to
+	type reference struct {
+		node    ast.Node
+		chapter int
+	}
+	var (
+		current     int
+		markers     []ast.Node
+		refs        []reference
+		combinedIDs = make([][]string, len(chapters))
+	)
+	_ = ast.Walk(doc.root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.HTMLBlock:
+			match := chapterMarkerPattern.FindStringSubmatch(strings.TrimSpace(string(blockText(n, doc.source))))
+			if match != nil {
+				current, _ = strconv.Atoi(match[1])
+				markers = append(markers, n)
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.Heading:
+			id, _ := headingID(n)
+			combinedIDs[current] = append(combinedIDs[current], id)
+		case *ast.Link, *ast.Image:
+			refs = append(refs, reference{n, current})
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// Map every chapter's own heading IDs to the IDs they received in the
+	// combined document, where duplicates across chapters are renumbered
+	ids := make([]map[string]string, len(chapters))
+	for i := range chapters {
+		ids[i] = make(map[string]string)
+		for j, heading := range standalone[i] {
+			if j >= len(combinedIDs[i]) {
+				break
+			}
+			// Accept GitHub style anchors too, which keep non-ASCII letters
+			ids[i][heading.slug] = combinedIDs[i][j]
+			if heading.id != "" {
+				ids[i][heading.id] = combinedIDs[i][j]
+			}
+		}
+	}
+	chapterIndex := make(map[string]int, len(chapters))
+	for i, abs := range chapters {
+		chapterIndex[abs] = i
+	}
+
+	for _, ref := range refs {
+		dir := filepath.Dir(chapters[ref.chapter])
+		switch n := ref.node.(type) {
+		case *ast.Link:
+			target, fragment, ok := localMarkdownLink(string(n.Destination))
+			if !ok {
+				break
+			}
+			chapter := ref.chapter
+			if target != "" {
+				index, found := chapterIndex[filepath.Join(dir, target)]
+				if !found {
+					break
+				}
+				chapter = index
+			}
+			if fragment == "" {
+				// A link to a whole chapter lands on its first heading
+				if len(combinedIDs[chapter]) > 0 && combinedIDs[chapter][0] != "" {
+					n.Destination = []byte("#" + combinedIDs[chapter][0])
+				}
+			} else if id, found := ids[chapter][fragment]; found {
+				n.Destination = []byte("#" + id)
+			}
+		case *ast.Image:
+			dest := string(n.Destination)
+			if u, err := url.Parse(dest); err == nil && u.Scheme == "" && u.Host == "" && dest != "" && !filepath.IsAbs(dest) {
+				n.Destination = []byte(filepath.Join(dir, filepath.FromSlash(u.Path)))
+			}
+		}
+	}
+
+	// Turn chapter markers into page breaks, unless the chapter's first
+	// heading already starts a new page
+	for _, marker := range markers {
+		parent := marker.Parent()
+		if next := marker.NextSibling(); next != nil && hasClass(next, classBreakBefore) {
+			parent.RemoveChild(parent, marker)
+		} else {
+			parent.ReplaceChild(parent, marker, &PageBreak{})
+		}
+	}
+
+	if err := applyFilters(ctx, doc, opts, format); err != nil {
+		return nil, err
+	}
+	if err := renderDiagrams(ctx, doc, opts); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// headingID returns the id attribute of a heading
+func headingID(n ast.Node) (string, bool) {
+	value, ok := n.AttributeString("id")
+	if !ok {
+		return "", false
+	}
+	switch v := value.(type) {
+	case []byte:
+		return string(v), true
+	case string:
+		return v, true
+	}
+	return "", false
+}
+
+// headingRef identifies a heading by its generated ID and its GitHub style slug
+type headingRef struct {
+	id   string
+	slug string
+}
+
+// headingRefs lists every heading of doc in document order
+func headingRefs(doc *document) []headingRef {
+	var refs []headingRef
+	_ = ast.Walk(doc.root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if heading, ok := n.(*ast.Heading); ok && entering {
+			id, _ := headingID(heading)
+			refs = append(refs, headingRef{id: id, slug: slugify(string(heading.Text(doc.source)))})
+		}
+		return ast.WalkContinue, nil
+	})
+	return refs
+}
+
+// slugify builds an anchor the way GitHub does: lower case letters, digits,
+// hyphens and underscores, with spaces turned into hyphens
+func slugify(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			sb.WriteRune(r)
+		case r == ' ':
+			sb.WriteByte('-')
+		}
+	}
+	return sb.String()
+}
+
+// ConvertBookToHTML renders all chapters of book into one HTML page
+func ConvertBookToHTML(book *Book, opts Options) (string, error) {
+	return ConvertBookToHTMLContext(context.Background(), book, opts)
+}
+
+// ConvertBookToHTMLContext is ConvertBookToHTML with cancellation. The
+// per-document timeout from opts applies to the whole book.
+func ConvertBookToHTMLContext(ctx context.Context, book *Book, opts Options) (string, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	pageOpts := opts
+	pageOpts.Metadata = withTitle(opts.Metadata, book.Title)
+
+	var html string
+	err := runContext(ctx, opts.Timeout, func() error {
+		doc, err := bookDocument(ctx, book, opts, FilterFormatHTML)
+		if err != nil {
+			return err
+		}
+		html, err = renderHTML(doc, pageOpts)
+		return err
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", contextError(ctx, opts.Timeout)
+		}
+		return "", err
+	}
+	return html, nil
+}
+
+// ConvertBookToPDF renders book to a PDF file. The renderer must implement
+// BookRenderer.
+func ConvertBookToPDF(renderer PDFRenderer, book *Book, outputPath string, opts Options) error {
+	return ConvertBookToPDFContext(context.Background(), renderer, book, outputPath, opts)
+}
+
+// ConvertBookToPDFContext is ConvertBookToPDF with cancellation. The
+// per-document timeout from opts applies to the whole book.
+func ConvertBookToPDFContext(ctx context.Context, renderer PDFRenderer, book *Book, outputPath string, opts Options) error {
+	bookRenderer, ok := renderer.(BookRenderer)
+	if !ok {
+		return fmt.Errorf("PDF engine does not support books")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	// The file is only written once rendering succeeded, so a renderer
+	// that outlives the context never touches it
+	var buf bytes.Buffer
+	var err error
+	if r, ok := bookRenderer.(ContextBookRenderer); ok {
+		err = r.RenderBookContext(ctx, &buf, book, opts)
+	} else {
+		err = runContext(ctx, opts.Timeout, func() error {
+			return bookRenderer.RenderBook(&buf, book, opts)
+		})
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return contextError(ctx, opts.Timeout)
+		}
+		return err
+	}
+
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write PDF file: %w", err)
+	}
+
+	return nil
+}
+
+// coverHTML returns the title page of a printed book. It is filled in like
+// the chapters' page, with the book's language, metadata, fonts and
+// watermark, so the two look alike.
+func coverHTML(book *Book, doc *document, opts Options) (string, error) {
+	fonts, err := fontCSS(opts.Fonts)
+	if err != nil {
+		return "", err
+	}
+	wm, err := resolveWatermark(opts.Watermark, doc.meta, opts.Includes)
+	if err != nil {
+		return "", err
+	}
+	stamp, stampCSS, err := watermarkHTML(wm)
+	if err != nil {
+		return "", err
+	}
+
+	page := pageOptions{css: fonts + stampCSS, bodyPrefix: stamp}
+	info := resolveMetadata(opts.Metadata, doc)
+	page.title, page.head = metadataHTML(info)
+	page.lang = languageHTML(info)
+
+	head, tail := newPageTemplate(opts.Theme).fill(page)
+	title := `<h1 class="display-4 text-center" style="margin-top: 35%;">` + html.EscapeString(book.Title) + "</h1>"
+	return head + title + tail, nil
+}
+
+// RenderBook implements BookRenderer. The table of contents, outline and
+// footer page numbers need a wkhtmltopdf build with patched Qt.
+func (r *WkhtmltopdfRenderer) RenderBook(w io.Writer, book *Book, opts Options) error {
+	return r.RenderBookContext(context.Background(), w, book, opts)
+}
+
+// RenderBookContext implements ContextBookRenderer; wkhtmltopdf is killed
+// when ctx is done
+func (r *WkhtmltopdfRenderer) RenderBookContext(ctx context.Context, w io.Writer, book *Book, opts Options) error {
+	opts = linkedFonts(opts)
+	opts.Metadata = withTitle(opts.Metadata, book.Title)
+	doc, err := bookDocument(ctx, book, opts, FilterFormatPDF)
+	if err != nil {
+		return err
+	}
+	return r.printDocument(ctx, w, doc, opts, func(workDir string, pdfg *wkhtmltopdf.PDFGenerator, page *wkhtmltopdf.Page) error {
+		if book.Title != "" {
+			cover := filepath.Join(workDir, "cover.html")
+			content, err := coverHTML(book, doc, opts)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(cover, []byte(content), 0644); err != nil {
+				return fmt.Errorf("failed to create cover page: %w", err)
+			}
+			pdfg.Cover.Input = cover
+			pdfg.Cover.EnableLocalFileAccess.Set(true)
+		}
+
+		pdfg.TOC.Include = true
+		pdfg.TOC.TocHeaderText.Set(book.tocTitle())
+		pdfg.TOC.FooterCenter.Set("[page]")
+		pdfg.TOC.FooterFontSize.Set(9)
+
+		page.FooterCenter.Set("[page]")
+		page.FooterFontSize.Set(9)
+		return nil
+	})
+}
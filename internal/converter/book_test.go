@@ -0,0 +1,25 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCoverHTMLMatchesChapters(t *testing.T) {
+	opts := Options{Metadata: &Metadata{Title: "Kitap", Language: "tr"}}
+	doc, err := parseDocument("# Bölüm\n", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cover, err := coverHTML(&Book{Title: "Kitap"}, doc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(cover, `lang="tr"`) {
+		t.Error("cover page has no language")
+	}
+	if !strings.Contains(cover, "<title>Kitap</title>") {
+		t.Error("cover page has no title")
+	}
+}
@@ -0,0 +1,446 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// chartLanguage is the fence language of charts, which are drawn in Go
+// instead of by a diagram command
+const chartLanguage = "chart"
+
+// Chart types
+const (
+	chartBar  = "bar"
+	chartLine = "line"
+	chartPie  = "pie"
+)
+
+// chartSpec is the data of a chart fence
+type chartSpec struct {
+	Type   string
+	Title  string
+	Labels []string
+	Series []chartSeries
+}
+
+// chartSeries is one named row of values, one per label
+type chartSeries struct {
+	Name   string
+	Values []float64
+}
+
+// chartYAML is the YAML form of a chart fence
+type chartYAML struct {
+	Type   string        `yaml:"type"`
+	Title  string        `yaml:"title"`
+	Labels []string      `yaml:"labels"`
+	Data   yaml.MapSlice `yaml:"data"` // Label: value pairs of a single series
+	Series []struct {
+		Name   string    `yaml:"name"`
+		Values []float64 `yaml:"values"`
+	} `yaml:"series"`
+}
+
+// parseChart reads a chart fence. YAML blocks have data or series keys;
+// anything else is CSV whose header row names the series, optionally after
+// "type:" and "title:" lines. kind is the type given in the info string,
+// as in ```chart pie, used when the block names none.
+func parseChart(source []byte, kind string) (*chartSpec, error) {
+	spec := &chartSpec{Type: kind}
+
+	var probe map[string]interface{}
+	if yaml.Unmarshal(source, &probe) == nil && (probe["data"] != nil || probe["series"] != nil) {
+		var doc chartYAML
+		if err := yaml.Unmarshal(source, &doc); err != nil {
+			return nil, fmt.Errorf("invalid chart: %w", err)
+		}
+		if doc.Type != "" {
+			spec.Type = doc.Type
+		}
+		spec.Title = doc.Title
+		spec.Labels = doc.Labels
+		for _, s := range doc.Series {
+			spec.Series = append(spec.Series, chartSeries{Name: s.Name, Values: s.Values})
+		}
+		if len(doc.Data) > 0 {
+			series := chartSeries{}
+			spec.Labels = nil
+			for _, item := range doc.Data {
+				value, err := chartNumber(fmt.Sprint(item.Value))
+				if err != nil {
+					return nil, err
+				}
+				spec.Labels = append(spec.Labels, fmt.Sprint(item.Key))
+				series.Values = append(series.Values, value)
+			}
+			spec.Series = append(spec.Series, series)
+		}
+	} else if err := parseChartCSV(spec, source); err != nil {
+		return nil, err
+	}
+
+	spec.Type = strings.ToLower(strings.TrimSpace(spec.Type))
+	if spec.Type == "" {
+		spec.Type = chartBar
+	}
+	switch spec.Type {
+	case chartBar, chartLine, chartPie:
+	default:
+		return nil, fmt.Errorf("unsupported chart type %q", spec.Type)
+	}
+	if len(spec.Series) == 0 || len(spec.Labels) == 0 {
+		return nil, fmt.Errorf("chart has no data")
+	}
+	for _, s := range spec.Series {
+		if len(s.Values) != len(spec.Labels) {
+			return nil, fmt.Errorf("chart series %q has %d values for %d labels", s.Name, len(s.Values), len(spec.Labels))
+		}
+		for _, v := range s.Values {
+			if err := checkChartValue(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if spec.Type == chartPie {
+		positive := false
+		for _, v := range spec.Series[0].Values {
+			positive = positive || v > 0
+		}
+		if !positive {
+			return nil, fmt.Errorf("pie chart has no positive values")
+		}
+	}
+	return spec, nil
+}
+
+// parseChartCSV fills spec from CSV data
+func parseChartCSV(spec *chartSpec, source []byte) error {
+	lines := strings.Split(strings.TrimSpace(string(source)), "\n")
+	for len(lines) > 0 {
+		key, value, ok := strings.Cut(lines[0], ":")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "type":
+			spec.Type = strings.TrimSpace(value)
+		case "title":
+			spec.Title = strings.TrimSpace(value)
+		default:
+			ok = false
+		}
+		if !ok {
+			break
+		}
+		lines = lines[1:]
+	}
+
+	if len(lines) == 0 {
+		return fmt.Errorf("chart has no data")
+	}
+
+	r := csv.NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+	if strings.Count(lines[0], ";") > strings.Count(lines[0], ",") {
+		r.Comma = ';'
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		return fmt.Errorf("invalid chart data: %w", err)
+	}
+	if len(records) < 2 || len(records[0]) < 2 {
+		return fmt.Errorf("chart data needs a header row and a label and value column")
+	}
+
+	for _, name := range records[0][1:] {
+		spec.Series = append(spec.Series, chartSeries{Name: strings.TrimSpace(name)})
+	}
+	for _, record := range records[1:] {
+		if len(record) != len(records[0]) {
+			return fmt.Errorf("chart row %q has %d columns, want %d", record[0], len(record), len(records[0]))
+		}
+		spec.Labels = append(spec.Labels, strings.TrimSpace(record[0]))
+		for i, field := range record[1:] {
+			value, err := chartNumber(field)
+			if err != nil {
+				return err
+			}
+			spec.Series[i].Values = append(spec.Series[i].Values, value)
+		}
+	}
+	return nil
+}
+
+// chartNumber reads a value such as "12.5", "40%" or an empty cell as zero
+func chartNumber(s string) (float64, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "%")
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid chart value %q", s)
+	}
+	if err := checkChartValue(v); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// chartLimit bounds chart values, so axis spans and pie totals stay finite
+const chartLimit = 1e15
+
+// checkChartValue rejects values that cannot be drawn
+func checkChartValue(v float64) error {
+	if math.IsNaN(v) || math.Abs(v) > chartLimit {
+		return fmt.Errorf("chart value %v is out of range", v)
+	}
+	return nil
+}
+
+// chartPalette holds the colours of a chart for one theme
+type chartPalette struct {
+	text   string
+	muted  string
+	grid   string
+	series []string
+}
+
+var chartPalettes = map[string]chartPalette{
+	ThemeLight: {
+		text:   "#212529",
+		muted:  "#6c757d",
+		grid:   "#dee2e6",
+		series: []string{"#0d6efd", "#dc3545", "#198754", "#fd7e14", "#6f42c1", "#20c997", "#d63384", "#ffc107"},
+	},
+	ThemeDark: {
+		text:   "#f8f9fa",
+		muted:  "#adb5bd",
+		grid:   "#495057",
+		series: []string{"#6ea8fe", "#ea868f", "#75b798", "#feb272", "#a98eda", "#79dfc1", "#e685b5", "#ffda6a"},
+	},
+}
+
+// Chart canvas size in user units
+const (
+	chartWidth  = 640
+	chartHeight = 360
+)
+
+// chartSVG draws a chart as an svg element in the colours of theme
+func chartSVG(spec *chartSpec, theme string) []byte {
+	palette, ok := chartPalettes[theme]
+	if !ok {
+		palette = chartPalettes[ThemeLight]
+	}
+	c := &chartCanvas{palette: palette}
+
+	fmt.Fprintf(&c.buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" role="img">`, chartWidth, chartHeight, chartWidth, chartHeight)
+	top := 16.0
+	if spec.Title != "" {
+		fmt.Fprintf(&c.buf, `<title>%s</title>`, html.EscapeString(spec.Title))
+		c.text(chartWidth/2, 26, spec.Title, "middle", 16, palette.text, true)
+		top = 44
+	}
+
+	if spec.Type == chartPie {
+		c.pie(spec, top)
+	} else {
+		if len(spec.Series) > 1 || spec.Series[0].Name != "" {
+			c.legend(spec, top)
+			top += 24
+		}
+		c.axes(spec, top)
+	}
+	c.buf.WriteString(`</svg>`)
+	return c.buf.Bytes()
+}
+
+// chartCanvas accumulates the SVG markup of a chart
+type chartCanvas struct {
+	buf     bytes.Buffer
+	palette chartPalette
+}
+
+func (c *chartCanvas) color(i int) string {
+	return c.palette.series[i%len(c.palette.series)]
+}
+
+func (c *chartCanvas) text(x, y float64, s, anchor string, size float64, fill string, bold bool) {
+	weight := ""
+	if bold {
+		weight = ` font-weight="bold"`
+	}
+	fmt.Fprintf(&c.buf, `<text x="%s" y="%s" text-anchor="%s" font-size="%s" fill="%s"%s>%s</text>`,
+		chartCoord(x), chartCoord(y), anchor, chartCoord(size), fill, weight, html.EscapeString(s))
+}
+
+// legend writes the series names in a row below the title
+func (c *chartCanvas) legend(spec *chartSpec, y float64) {
+	x := 60.0
+	for i, s := range spec.Series {
+		fmt.Fprintf(&c.buf, `<rect x="%s" y="%s" width="12" height="12" fill="%s"/>`, chartCoord(x), chartCoord(y), c.color(i))
+		c.text(x+18, y+10.5, s.Name, "start", 12, c.palette.text, false)
+		x += 36 + 7*float64(len([]rune(s.Name)))
+	}
+}
+
+// axes draws a bar or line chart with a value axis below top
+func (c *chartCanvas) axes(spec *chartSpec, top float64) {
+	const left, right, bottom = 60.0, chartWidth - 20.0, chartHeight - 40.0
+
+	low, high := 0.0, 0.0
+	for _, s := range spec.Series {
+		for _, v := range s.Values {
+			low, high = math.Min(low, v), math.Max(high, v)
+		}
+	}
+	ticks := chartTicks(low, high)
+	low, high = ticks[0], ticks[len(ticks)-1]
+	if high <= low {
+		high = low + 1
+	}
+	y := func(v float64) float64 {
+		return bottom - (v-low)/(high-low)*(bottom-top)
+	}
+
+	// Grid lines and value labels
+	for _, tick := range ticks {
+		fmt.Fprintf(&c.buf, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="%s" stroke-width="1"/>`,
+			chartCoord(left), chartCoord(y(tick)), chartCoord(right), chartCoord(y(tick)), c.palette.grid)
+		c.text(left-8, y(tick)+4, chartLabel(tick), "end", 11, c.palette.muted, false)
+	}
+
+	step := (right - left) / float64(len(spec.Labels))
+	for i, label := range spec.Labels {
+		c.text(left+step*(float64(i)+0.5), bottom+18, label, "middle", 11, c.palette.text, false)
+	}
+
+	switch spec.Type {
+	case chartBar:
+		group := step * 0.7
+		width := group / float64(len(spec.Series))
+		for si, s := range spec.Series {
+			for i, v := range s.Values {
+				x := left + step*float64(i) + (step-group)/2 + width*float64(si)
+				y0, y1 := y(math.Max(v, 0)), y(math.Min(v, 0))
+				fmt.Fprintf(&c.buf, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"><title>%s</title></rect>`,
+					chartCoord(x), chartCoord(y0), chartCoord(width*0.9), chartCoord(y1-y0), c.color(si),
+					html.EscapeString(chartTooltip(s.Name, spec.Labels[i], v)))
+			}
+		}
+	case chartLine:
+		for si, s := range spec.Series {
+			points := make([]string, len(s.Values))
+			for i, v := range s.Values {
+				points[i] = chartCoord(left+step*(float64(i)+0.5)) + "," + chartCoord(y(v))
+			}
+			fmt.Fprintf(&c.buf, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2.5"/>`, strings.Join(points, " "), c.color(si))
+			for i, v := range s.Values {
+				fmt.Fprintf(&c.buf, `<circle cx="%s" cy="%s" r="3.5" fill="%s"><title>%s</title></circle>`,
+					chartCoord(left+step*(float64(i)+0.5)), chartCoord(y(v)), c.color(si),
+					html.EscapeString(chartTooltip(s.Name, spec.Labels[i], v)))
+			}
+		}
+	}
+
+	// Zero line on top of the bars
+	fmt.Fprintf(&c.buf, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="%s" stroke-width="1.5"/>`,
+		chartCoord(left), chartCoord(y(0)), chartCoord(right), chartCoord(y(0)), c.palette.muted)
+}
+
+// pie draws the first series as slices with a legend of shares
+func (c *chartCanvas) pie(spec *chartSpec, top float64) {
+	values := spec.Series[0].Values
+	total := 0.0
+	for _, v := range values {
+		total += math.Max(v, 0)
+	}
+	radius := (chartHeight - top - 20) / 2
+	cx, cy := 40+radius, top+radius
+
+	angle := -math.Pi / 2
+	legendY := top + 10
+	for i, v := range values {
+		if v <= 0 {
+			continue
+		}
+		share := v / total
+		label := html.EscapeString(chartTooltip("", spec.Labels[i], v))
+		if share >= 0.9999 {
+			fmt.Fprintf(&c.buf, `<circle cx="%s" cy="%s" r="%s" fill="%s"><title>%s</title></circle>`,
+				chartCoord(cx), chartCoord(cy), chartCoord(radius), c.color(i), label)
+		} else {
+			end := angle + share*2*math.Pi
+			large := 0
+			if share > 0.5 {
+				large = 1
+			}
+			fmt.Fprintf(&c.buf, `<path d="M%s,%s L%s,%s A%s,%s 0 %d 1 %s,%s Z" fill="%s" stroke="%s" stroke-width="1"><title>%s</title></path>`,
+				chartCoord(cx), chartCoord(cy),
+				chartCoord(cx+radius*math.Cos(angle)), chartCoord(cy+radius*math.Sin(angle)),
+				chartCoord(radius), chartCoord(radius), large,
+				chartCoord(cx+radius*math.Cos(end)), chartCoord(cy+radius*math.Sin(end)),
+				c.color(i), c.palette.grid, label)
+			angle = end
+		}
+
+		x := cx + radius + 40
+		fmt.Fprintf(&c.buf, `<rect x="%s" y="%s" width="12" height="12" fill="%s"/>`, chartCoord(x), chartCoord(legendY), c.color(i))
+		c.text(x+18, legendY+10.5, fmt.Sprintf("%s (%s%%)", spec.Labels[i], chartLabel(math.Round(share*1000)/10)), "start", 12, c.palette.text, false)
+		legendY += 22
+	}
+}
+
+// chartTicks returns evenly spaced round values covering low to high, at
+// least two of them
+func chartTicks(low, high float64) []float64 {
+	if math.IsNaN(low) || math.IsNaN(high) || math.IsInf(high-low, 0) {
+		return []float64{0, 1}
+	}
+	if high <= low {
+		high = low + 1
+	}
+	raw := (high - low) / 5
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := magnitude * 10
+	for _, f := range []float64{1, 2, 2.5, 5} {
+		if raw <= f*magnitude {
+			step = f * magnitude
+			break
+		}
+	}
+
+	var ticks []float64
+	for v := math.Floor(low/step) * step; v < high+step/2 && len(ticks) < 20; v += step {
+		ticks = append(ticks, math.Round(v/step)*step)
+	}
+	if len(ticks) < 2 {
+		return []float64{low, high}
+	}
+	return ticks
+}
+
+// chartLabel formats an axis value without trailing zeros
+func chartLabel(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
+
+// chartTooltip is the hover text of a data point
+func chartTooltip(series, label string, v float64) string {
+	if series != "" {
+		return fmt.Sprintf("%s, %s: %s", series, label, chartLabel(v))
+	}
+	return fmt.Sprintf("%s: %s", label, chartLabel(v))
+}
+
+// chartCoord formats a coordinate with at most two decimals
+func chartCoord(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,344 @@
+package converter
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// codeInfo is the presentation of a fenced code block, read from the
+// attributes of its info string: go title="main.go" {3,5-7} linenos copy
+type codeInfo struct {
+	language    []byte
+	title       string
+	highlight   map[int]bool // Emphasized lines, counted from 1
+	lineNumbers bool
+	copy        bool
+}
+
+// enhanced reports whether the block needs more than a bare <pre><code>
+func (i *codeInfo) enhanced() bool {
+	return i.title != "" || i.lineNumbers || len(i.highlight) > 0 || i.copy
+}
+
+// parseCodeInfo reads the info string of n. copy is the default for blocks
+// that ask for neither copy nor nocopy.
+func parseCodeInfo(n *ast.FencedCodeBlock, source []byte, copy bool) codeInfo {
+	info := codeInfo{language: n.Language(source), copy: copy}
+	if n.Info == nil {
+		return info
+	}
+
+	rest := fenceArgPattern.ReplaceAllStringFunc(string(n.Info.Segment.Value(source)), func(arg string) string {
+		m := fenceArgPattern.FindStringSubmatch(arg)
+		if m[1] == "title" {
+			info.title = strings.Trim(m[2], `"`)
+		}
+		return ""
+	})
+	if bytes.ContainsRune(info.language, '=') {
+		info.language = nil
+	}
+
+	for _, field := range strings.Fields(rest) {
+		switch field {
+		case "linenos":
+			info.lineNumbers = true
+		case "copy":
+			info.copy = true
+		case "nocopy":
+			info.copy = false
+		default:
+			if open := strings.IndexByte(field, '{'); open >= 0 && strings.HasSuffix(field, "}") {
+				info.highlight = parseLineSet(field[open+1 : len(field)-1])
+			}
+		}
+	}
+	// A block may have attributes but no language
+	switch string(info.language) {
+	case "linenos", "copy", "nocopy":
+		info.language = nil
+	}
+	// The language may carry the line set, as in go{3,5-7}
+	if open := bytes.IndexByte(info.language, '{'); open >= 0 {
+		info.language = info.language[:open]
+	}
+	if len(info.language) == 0 {
+		info.language = nil
+	}
+	return info
+}
+
+// parseLineSet reads a list of lines and ranges such as 3,5-7
+func parseLineSet(s string) map[int]bool {
+	lines := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			continue
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil {
+				continue
+			}
+		}
+		for line := start; line <= end && line-start < 10000; line++ {
+			lines[line] = true
+		}
+	}
+	return lines
+}
+
+// Classes of enhanced code block markup
+const (
+	classCodeBlock  = "code-block"
+	classCodeHeader = "code-header"
+	classCodeTitle  = "code-title"
+	classCodeBody   = "code-body"
+	classCodeGutter = "code-gutter"
+	classCodeMark   = "code-mark"
+	classCodeCopy   = "code-copy"
+)
+
+// Geometry of code lines in em, shared by the CSS and the line marks
+const (
+	codePadding    = 1.0
+	codeLineHeight = 1.5
+)
+
+// codeBlockHTMLRenderer writes fenced code blocks. Blocks without
+// attributes keep goldmark's markup; the rest get a title bar, a line
+// number gutter, marks behind emphasized lines and a copy button. The code
+// element itself stays plain so Prism can still highlight it.
+type codeBlockHTMLRenderer struct {
+	copy bool // Copy buttons on every block
+}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *codeBlockHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.render)
+}
+
+func (r *codeBlockHTMLRenderer) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.FencedCodeBlock)
+	info := parseCodeInfo(n, source, r.copy)
+	if !info.enhanced() {
+		_, _ = w.WriteString("<pre><code")
+		if language := n.Language(source); language != nil {
+			_, _ = w.WriteString(` class="language-`)
+			html.DefaultWriter.Write(w, language)
+			_ = w.WriteByte('"')
+		}
+		_ = w.WriteByte('>')
+		writeCodeLines(w, source, n)
+		_, _ = w.WriteString("</code></pre>\n")
+		return ast.WalkSkipChildren, nil
+	}
+
+	_, _ = w.WriteString(`<div class="` + classCodeBlock + `">` + "\n")
+	if info.title != "" {
+		_, _ = w.WriteString(`<div class="` + classCodeHeader + `"><span class="` + classCodeTitle + `">`)
+		_, _ = w.Write(util.EscapeHTML([]byte(info.title)))
+		_, _ = w.WriteString("</span></div>\n")
+	}
+	_, _ = w.WriteString(`<div class="` + classCodeBody + `">` + "\n")
+
+	count := n.Lines().Len()
+	if info.lineNumbers {
+		_, _ = w.WriteString(`<pre class="` + classCodeGutter + `" aria-hidden="true">`)
+		for i := 1; i <= count; i++ {
+			if i > 1 {
+				_ = w.WriteByte('\n')
+			}
+			_, _ = w.WriteString(strconv.Itoa(i))
+		}
+		_, _ = w.WriteString("</pre>\n")
+	}
+
+	_, _ = w.WriteString("<pre><code")
+	if info.language != nil {
+		_, _ = w.WriteString(` class="language-`)
+		html.DefaultWriter.Write(w, info.language)
+		_ = w.WriteByte('"')
+	}
+	_ = w.WriteByte('>')
+	writeCodeLines(w, source, n)
+	_, _ = w.WriteString("</code>")
+	// One mark per run of emphasized lines, outside the code element that
+	// Prism rewrites
+	for line := 1; line <= count; line++ {
+		if !info.highlight[line] {
+			continue
+		}
+		start := line
+		for line < count && info.highlight[line+1] {
+			line++
+		}
+		top := strconv.FormatFloat(codePadding+codeLineHeight*float64(start-1), 'f', -1, 64)
+		height := strconv.FormatFloat(codeLineHeight*float64(line-start+1), 'f', -1, 64)
+		_, _ = w.WriteString(`<span class="` + classCodeMark + `" style="top: ` + top + `em; height: ` + height + `em"></span>`)
+	}
+	_, _ = w.WriteString("</pre>\n")
+
+	if info.copy {
+		_, _ = w.WriteString(`<button type="button" class="` + classCodeCopy + `" aria-label="Copy code">Copy</button>` + "\n")
+	}
+	_, _ = w.WriteString("</div>\n</div>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+// writeCodeLines writes the escaped lines of a code block
+func writeCodeLines(w util.BufWriter, source []byte, n ast.Node) {
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		html.DefaultWriter.RawWrite(w, line.Value(source))
+	}
+}
+
+// codeBlockExtension renders fenced code blocks with their attributes
+type codeBlockExtension struct {
+	copy bool
+}
+
+// Extend implements goldmark.Extender
+func (e *codeBlockExtension) Extend(m goldmark.Markdown) {
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&codeBlockHTMLRenderer{copy: e.copy}, 500),
+	))
+}
+
+// codeBlockFeatures reports whether doc has code blocks with attributes
+// and whether any of them has a copy button
+func codeBlockFeatures(doc *document, copy bool) (enhanced, copyButtons bool) {
+	_ = ast.Walk(doc.root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if block, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			info := parseCodeInfo(block, doc.source, copy)
+			enhanced = enhanced || info.enhanced()
+			copyButtons = copyButtons || info.copy
+		}
+		return ast.WalkContinue, nil
+	})
+	return enhanced, copyButtons
+}
+
+// codeBlockCSS lays out titled, numbered and marked code blocks. The
+// gutter floats next to the code, since wkhtmltopdf has no flexbox.
+func codeBlockCSS(theme string) string {
+	header, title, gutter, border, button := "#e9ecef", "#495057", "#adb5bd", "#dee2e6", "#ffffff"
+	if theme == ThemeDark {
+		header, title, gutter, border, button = "#1a202c", "#e2e8f0", "#718096", "#4a5568", "#343a40"
+	}
+	return `
+		.markdown-content .code-block {
+			margin-bottom: 1rem;
+			page-break-inside: avoid;
+		}
+		.markdown-content .code-header {
+			padding: 0.4rem 1rem;
+			border-radius: 0.5rem 0.5rem 0 0;
+			background-color: ` + header + `;
+			color: ` + title + `;
+			font-family: SFMono-Regular, Menlo, Consolas, monospace;
+			font-size: 0.8rem;
+		}
+		.markdown-content .code-body {
+			position: relative;
+		}
+		.markdown-content .code-body:after {
+			content: "";
+			display: block;
+			clear: both;
+		}
+		.markdown-content .code-block .code-body pre {
+			margin: 0;
+			padding: ` + strconv.FormatFloat(codePadding, 'f', -1, 64) + `em;
+			font-size: 0.875rem;
+			line-height: ` + strconv.FormatFloat(codeLineHeight, 'f', -1, 64) + `;
+		}
+		.markdown-content .code-block .code-body pre code {
+			font-size: inherit;
+			line-height: inherit;
+		}
+		.markdown-content .code-block .code-body pre:not(.code-gutter) {
+			position: relative;
+			overflow-x: auto;
+		}
+		.markdown-content .code-header + .code-body pre {
+			border-top-left-radius: 0;
+			border-top-right-radius: 0;
+		}
+		.markdown-content .code-block .code-gutter {
+			float: left;
+			padding-right: 0.75em;
+			border-right: 1px solid ` + border + `;
+			border-top-right-radius: 0;
+			border-bottom-right-radius: 0;
+			color: ` + gutter + `;
+			text-align: right;
+			-webkit-user-select: none;
+			user-select: none;
+		}
+		.markdown-content .code-gutter + pre {
+			border-top-left-radius: 0;
+			border-bottom-left-radius: 0;
+		}
+		.markdown-content .code-mark {
+			position: absolute;
+			left: 0;
+			right: 0;
+			border-left: 3px solid #ffc107;
+			background-color: rgba(255, 193, 7, 0.18);
+			pointer-events: none;
+		}
+		.markdown-content .code-copy {
+			position: absolute;
+			top: 0.5rem;
+			right: 0.5rem;
+			padding: 0.1rem 0.5rem;
+			border: 1px solid ` + border + `;
+			border-radius: 0.25rem;
+			background-color: ` + button + `;
+			color: ` + title + `;
+			font-size: 0.75rem;
+			opacity: 0;
+			transition: opacity 0.15s;
+		}
+		.markdown-content .code-body:hover .code-copy,
+		.markdown-content .code-copy:focus {
+			opacity: 1;
+		}`
+}
+
+// codePrintCSS hides copy buttons on printed pages
+const codePrintCSS = `
+		.markdown-content .code-copy {
+			display: none;
+		}`
+
+// codeCopyScript copies a block's code when its button is clicked
+const codeCopyScript = `
+    <script>
+        document.addEventListener("click", function (event) {
+            var button = event.target.closest ? event.target.closest(".code-copy") : null;
+            if (!button || !navigator.clipboard) {
+                return;
+            }
+            var code = button.parentNode.querySelector("pre:not(.code-gutter) code");
+            navigator.clipboard.writeText(code.textContent).then(function () {
+                button.textContent = "Copied";
+                setTimeout(function () { button.textContent = "Copy"; }, 1500);
+            });
+        });
+    </script>`
@@ -0,0 +1,254 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Arguments of a code block showing a source file, as in
+// ```go file=../cmd/main.go lines=20-40 or ```file=app.py region=setup
+const (
+	codeFileArg   = "file"
+	codeLinesArg  = "lines"
+	codeRegionArg = "region"
+)
+
+// fenceArgPattern matches the key=value arguments of a fence info string
+var fenceArgPattern = regexp.MustCompile(`(\w+)=("[^"]*"|\S+)`)
+
+// codeLinesPattern matches a line range such as 20-40, 20-, -40 or 7
+var codeLinesPattern = regexp.MustCompile(`^([0-9]*)(-?)([0-9]*)$`)
+
+// regionPattern matches a region marker comment such as // #region setup,
+// # region setup or <!-- #endregion -->
+var regionPattern = regexp.MustCompile(`^[ \t]*(?://|#|--|/\*|<!--|;|%|')[ \t]*#?[ \t]*(end)?region\b[ \t]*([\w.-]*)`)
+
+// codeLanguages maps file extensions to fence languages where the two
+// differ
+var codeLanguages = map[string]string{
+	".bash":       "bash",
+	".cc":         "cpp",
+	".cs":         "csharp",
+	".dockerfile": "docker",
+	".ex":         "elixir",
+	".exs":        "elixir",
+	".h":          "c",
+	".hpp":        "cpp",
+	".hs":         "haskell",
+	".js":         "javascript",
+	".kt":         "kotlin",
+	".md":         "markdown",
+	".mjs":        "javascript",
+	".pl":         "perl",
+	".ps1":        "powershell",
+	".py":         "python",
+	".rb":         "ruby",
+	".rs":         "rust",
+	".sh":         "bash",
+	".tf":         "hcl",
+	".ts":         "typescript",
+	".yml":        "yaml",
+	".zsh":        "bash",
+}
+
+// codeLanguage infers the fence language of a file from its name
+func codeLanguage(path string) string {
+	switch base := strings.ToLower(filepath.Base(path)); base {
+	case "dockerfile":
+		return "docker"
+	case "makefile", "gnumakefile":
+		return "makefile"
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if language, ok := codeLanguages[ext]; ok {
+		return language
+	}
+	return strings.TrimPrefix(ext, ".")
+}
+
+// codeFileArgs splits the file, lines and region arguments off a fence
+// info string, returning them and the rest of the info string. A block is
+// filled from a file only if args has a file key.
+func codeFileArgs(info string) (map[string]string, string) {
+	args := make(map[string]string)
+	rest := fenceArgPattern.ReplaceAllStringFunc(info, func(arg string) string {
+		m := fenceArgPattern.FindStringSubmatch(arg)
+		switch m[1] {
+		case codeFileArg, codeLinesArg, codeRegionArg:
+			args[m[1]] = strings.Trim(m[2], `"`)
+			return ""
+		}
+		return arg
+	})
+	return args, rest
+}
+
+// codeFile writes a fenced code block filled from the file its info string
+// names, relative to dir. The block's own content is dropped. region keeps
+// the lines between the region's markers; lines then picks a range, counted
+// from the start of the region if there is one.
+func (x *includer) codeFile(sb *strings.Builder, line, marker string, args map[string]string, rest, dir string) error {
+	path := args[codeFileArg]
+	if path == "" {
+		return fmt.Errorf("code block has an empty %s argument", codeFileArg)
+	}
+
+	real, err := x.resolve(path, dir)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(real)
+	if err != nil {
+		return fmt.Errorf("failed to include %s: %w", path, err)
+	}
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	lines := strings.Split(text, "\n")
+
+	if region := args[codeRegionArg]; region != "" {
+		if lines, err = codeRegion(lines, region); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if spec := args[codeLinesArg]; spec != "" {
+		if lines, err = codeLines(lines, spec); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	lines = dedent(lines)
+
+	// The language defaults to the file's, ahead of any other arguments
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || !isCodeLanguage(fields[0]) {
+		if language := codeLanguage(path); language != "" {
+			fields = append([]string{language}, fields...)
+		}
+	}
+
+	// The fence must outlast any run of its character in the file
+	longest := 0
+	for _, l := range lines {
+		run := 0
+		for i := 0; i < len(l); i++ {
+			if l[i] == marker[0] {
+				run++
+				if run > longest {
+					longest = run
+				}
+			} else {
+				run = 0
+			}
+		}
+	}
+	fence := marker
+	if longest >= len(fence) {
+		fence = strings.Repeat(marker[:1], longest+1)
+	}
+
+	indent := line[:strings.Index(line, marker)]
+	sb.WriteString(indent + fence + strings.Join(fields, " ") + "\n")
+	for _, l := range lines {
+		sb.WriteString(indent + l + "\n")
+	}
+	sb.WriteString(indent + fence + "\n")
+	return nil
+}
+
+// codeRegion returns the lines between the markers of region, leaving out
+// the marker lines of any nested region
+func codeRegion(lines []string, region string) ([]string, error) {
+	var (
+		out    []string
+		inside bool
+		depth  int // Regions opened inside the wanted one
+	)
+	for _, line := range lines {
+		m := regionPattern.FindStringSubmatch(line)
+		if !inside {
+			if m != nil && m[1] == "" && m[2] == region {
+				inside = true
+			}
+			continue
+		}
+		if m == nil {
+			out = append(out, line)
+			continue
+		}
+		if m[1] == "" {
+			depth++
+			continue
+		}
+		if m[2] == region || m[2] == "" && depth == 0 {
+			return out, nil
+		}
+		if depth > 0 {
+			depth--
+		}
+	}
+	if !inside {
+		return nil, fmt.Errorf("region %q not found", region)
+	}
+	return nil, fmt.Errorf("region %q is not closed", region)
+}
+
+// codeLines returns the 1-based, inclusive line range spec of lines
+func codeLines(lines []string, spec string) ([]string, error) {
+	m := codeLinesPattern.FindStringSubmatch(spec)
+	if m == nil || m[1] == "" && m[3] == "" {
+		return nil, fmt.Errorf("invalid line range %q", spec)
+	}
+	start, end := 1, len(lines)
+	if m[1] != "" {
+		start, _ = strconv.Atoi(m[1])
+	}
+	if m[3] != "" {
+		end, _ = strconv.Atoi(m[3])
+	} else if m[2] == "" {
+		end = start
+	}
+	if start < 1 || end < start || end > len(lines) {
+		return nil, fmt.Errorf("lines %s out of range, the file has %d lines", spec, len(lines))
+	}
+	return lines[start-1 : end], nil
+}
+
+// dedent removes the indentation shared by all non-blank lines
+func dedent(lines []string) []string {
+	prefix := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if prefix == "" {
+		return lines
+	}
+
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = strings.TrimPrefix(line, prefix)
+	}
+	return out
+}
+
+// isCodeLanguage reports whether the first field of an info string names
+// a language, rather than an attribute or line set as in {3}
+func isCodeLanguage(field string) bool {
+	switch field {
+	case "linenos", "copy", "nocopy":
+		return false
+	}
+	return !strings.Contains(field, "=") && !strings.HasPrefix(field, "{")
+}
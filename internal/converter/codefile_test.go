@@ -0,0 +1,32 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodeFileInfersLanguage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x.go"), []byte("package x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"```file=x.go\n```\n":             "```go\n",
+		"```file=x.go {3}\n```\n":         "```go {3}\n",
+		"```file=x.go linenos\n```\n":     "```go linenos\n",
+		"```text file=x.go {3}\n```\n":    "```text {3}\n",
+		"```file=x.go title=\"x\"\n```\n": "```go title=\"x\"\n",
+	}
+	for source, want := range tests {
+		out, err := expandIncludes([]byte(source), &Includes{Root: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(out), want) {
+			t.Errorf("%q expanded to %q, want it to open with %q", source, out, want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigFileName is the per-project configuration file, looked up in the
+// directory of the input and its parents
+const ConfigFileName = ".markdown-to-html.yml"
+
+// Config holds per-project settings read from a configuration file
+type Config struct {
+	// Extensions are enabled in addition to those given on the command line
+	Extensions []string `yaml:"extensions"`
+
+	// References sets the URL templates for issue, mention and commit links
+	References *References `yaml:"references"`
+}
+
+// LoadConfig reads a configuration file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if _, err := namedExtenders(cfg.Extensions); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// FindConfig returns the path of the nearest ConfigFileName in dir or its
+// parents, or an empty string if there is none
+func FindConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ConfigFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,192 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"markdown-to-html/internal/utils"
+)
+
+// ContextPDFRenderer is implemented by PDF renderers that stop work when a
+// context is cancelled
+type ContextPDFRenderer interface {
+	// RenderPDFContext is RenderPDF with cancellation; external processes
+	// are killed when ctx is done
+	RenderPDFContext(ctx context.Context, w io.Writer, markdown string, opts Options) error
+}
+
+// withTimeout applies the per-document timeout, if any
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// contextError explains why a conversion stopped early
+func contextError(ctx context.Context, timeout time.Duration) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && timeout > 0 {
+		return fmt.Errorf("conversion timed out after %s: %w", timeout, ctx.Err())
+	}
+	return fmt.Errorf("conversion cancelled: %w", ctx.Err())
+}
+
+// runContext runs fn, which cannot be interrupted, and stops waiting for it
+// when ctx is done. fn must not touch caller-owned state such as an output
+// writer, because it may still be running after runContext returns.
+func runContext(ctx context.Context, timeout time.Duration, fn func() error) error {
+	if ctx.Err() != nil {
+		return contextError(ctx, timeout)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return contextError(ctx, timeout)
+	}
+}
+
+// ConvertToHTMLContext is ConvertToHTMLWithOptions with cancellation and the
+// per-document timeout from opts
+func ConvertToHTMLContext(ctx context.Context, markdown string, opts Options) (string, error) {
+	c, err := NewConverter(opts)
+	if err != nil {
+		return "", err
+	}
+	return c.ConvertToHTMLContext(ctx, markdown)
+}
+
+// ConvertToHTMLContext is ConvertToHTML with cancellation and the
+// per-document timeout from the Converter's options
+func (c *Converter) ConvertToHTMLContext(ctx context.Context, markdown string) (string, error) {
+	ctx, cancel := withTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	var html string
+	err := runContext(ctx, c.opts.Timeout, func() error {
+		var err error
+		html, err = c.convertToHTML(ctx, markdown)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return html, nil
+}
+
+// RenderPDFContext renders markdown with renderer, giving up when ctx is
+// done or the per-document timeout from opts expires. Nothing is written to
+// w on failure.
+func RenderPDFContext(ctx context.Context, renderer PDFRenderer, w io.Writer, markdown string, opts Options) error {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	if r, ok := renderer.(ContextPDFRenderer); ok {
+		if err := r.RenderPDFContext(ctx, w, markdown, opts); err != nil {
+			if ctx.Err() != nil {
+				return contextError(ctx, opts.Timeout)
+			}
+			return err
+		}
+		return nil
+	}
+
+	// Render into a private buffer so a renderer that outlives the
+	// context never writes to w
+	var buf bytes.Buffer
+	err := runContext(ctx, opts.Timeout, func() error {
+		return renderer.RenderPDF(&buf, markdown, opts)
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write PDF output: %w", err)
+	}
+	return nil
+}
+
+// ConvertToPDFContext is ConvertToPDFWith with cancellation and the
+// per-document timeout from opts
+func ConvertToPDFContext(ctx context.Context, renderer PDFRenderer, markdown string, outputPath string, opts Options) error {
+	// Ensure output directory exists
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderPDFContext(ctx, renderer, &buf, markdown, opts); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write PDF file: %w", err)
+	}
+	return nil
+}
+
+// ConvertMultipleFilesContext is ConvertMultipleFiles with cancellation. The
+// per-document timeout from opts applies to each file separately.
+func ConvertMultipleFilesContext(ctx context.Context, inputFiles []string, outputDir string, opts Options) error {
+	return ConvertFiles(ctx, inputFiles, outputDir, opts, func(inputFile, outputFile string) {
+		fmt.Printf("Converted %s to %s\n", inputFile, outputFile)
+	})
+}
+
+// ConvertFiles converts markdown files to HTML pages in outputDir and calls
+// converted, if not nil, after each file is written. It stops at the first
+// error.
+func ConvertFiles(ctx context.Context, inputFiles []string, outputDir string, opts Options, converted func(inputFile, outputFile string)) error {
+	// One converter serves the whole batch
+	c, err := NewConverter(opts)
+	if err != nil {
+		return err
+	}
+
+	for _, inputFile := range inputFiles {
+		if ctx.Err() != nil {
+			return contextError(ctx, 0)
+		}
+
+		// Read markdown file
+		content, err := utils.ReadFile(inputFile)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", inputFile, err)
+		}
+
+		// Convert to HTML
+		html, err := c.ConvertToHTMLContext(ctx, content)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s: %w", inputFile, err)
+		}
+
+		// Generate output filename
+		base := filepath.Base(inputFile)
+		ext := filepath.Ext(base)
+		outputFile := filepath.Join(outputDir, strings.TrimSuffix(base, ext)+".html")
+
+		// Write output file
+		if err := utils.WriteFile(outputFile, html); err != nil {
+			return fmt.Errorf("failed to write %s: %w", outputFile, err)
+		}
+
+		if converted != nil {
+			converted(inputFile, outputFile)
+		}
+	}
+
+	return nil
+}
@@ -1,24 +1,66 @@
 package converter
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"path/filepath"
+	"io"
 	"strings"
 
-	"markdown-to-html/internal/utils"
-
 	"github.com/yuin/goldmark"
+	meta "github.com/yuin/goldmark-meta"
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
 )
 
-// ConvertToHTML converts markdown content to HTML with Bootstrap styling
-func ConvertToHTML(markdown string, theme string) (string, error) {
+// cdnResources are the external assets referenced by the HTML wrapper
+var cdnResources = []string{
+	"https://cdn.jsdelivr.net/npm/bootstrap@5.3.0/dist/css/bootstrap.min.css",
+	"https://cdn.jsdelivr.net/npm/prismjs@1.29.0/themes/prism.min.css",
+	"https://cdn.jsdelivr.net/npm/bootstrap@5.3.0/dist/js/bootstrap.bundle.min.js",
+	"https://cdn.jsdelivr.net/npm/prismjs@1.29.0/components/prism-core.min.js",
+	"https://cdn.jsdelivr.net/npm/prismjs@1.29.0/plugins/autoloader/prism-autoloader.min.js",
+}
+
+// CDNResources returns the external assets the generated HTML loads. PDF
+// output only looks right when wkhtmltopdf can fetch them.
+func CDNResources() []string {
+	return append([]string(nil), cdnResources...)
+}
+
+// newMarkdown creates the goldmark instance shared by the HTML and PDF pipelines
+func newMarkdown(opts Options) (goldmark.Markdown, error) {
+	named, err := namedExtenders(opts.Extensions)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create markdown parser with extensions
-	md := goldmark.New(
-		goldmark.WithExtensions(extension.GFM), // GitHub Flavored Markdown
+	extensions := []goldmark.Extender{
+		extension.GFM, // GitHub Flavored Markdown
+		meta.Meta,     // YAML front matter
+		&pageBreakExtension{breakBefore: opts.BreakBefore},
+		&admonitionExtension{}, // GitHub alerts and ::: containers
+		&diagramExtension{},    // Diagram fences rendered by renderDiagrams
+		&codeBlockExtension{copy: opts.CopyButtons},
+	}
+	extensions = append(extensions, named...)
+	for _, name := range opts.Extensions {
+		if strings.EqualFold(name, ExtTypographer) {
+			extensions = append(extensions, &localQuotesExtension{language: configuredLanguage(opts.Metadata)})
+			break
+		}
+	}
+	if opts.References != nil {
+		extensions = append(extensions, &referenceExtension{refs: opts.References})
+	}
+	extensions = append(extensions, opts.Extenders...)
+
+	return goldmark.New(
+		goldmark.WithExtensions(extensions...),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
 		),
@@ -26,27 +68,325 @@ func ConvertToHTML(markdown string, theme string) (string, error) {
 			html.WithHardWraps(),
 			html.WithXHTML(),
 		),
-	)
+	), nil
+}
 
-	// Convert markdown to HTML
-	var buf bytes.Buffer
-	if err := md.Convert([]byte(markdown), &buf); err != nil {
-		return "", fmt.Errorf("failed to convert markdown: %w", err)
+// document is a parsed markdown source ready for rendering
+type document struct {
+	md     goldmark.Markdown
+	source []byte
+	root   ast.Node
+	meta   map[string]interface{} // Front matter, nil if there is none
+}
+
+// parseDocument expands includes, then parses markdown and applies all AST
+// transformations
+func parseDocument(markdown string, opts Options) (*document, error) {
+	md, err := newMarkdown(opts)
+	if err != nil {
+		return nil, err
+	}
+	source, err := expandIncludes([]byte(markdown), opts.Includes)
+	if err != nil {
+		return nil, err
+	}
+	return parseWith(md, source), nil
+}
+
+// parseWith parses source with an existing goldmark instance
+func parseWith(md goldmark.Markdown, source []byte) *document {
+	pc := parser.NewContext()
+	root := md.Parser().Parse(text.NewReader(source), parser.WithContext(pc))
+
+	return &document{
+		md:     md,
+		source: source,
+		root:   root,
+		meta:   meta.Get(pc),
+	}
+}
+
+// Converter converts markdown to HTML with fixed options. It builds the
+// goldmark instance, font rules and page template once, so reusing one
+// Converter for many documents is much cheaper than the package-level
+// functions. A Converter is safe for concurrent use.
+type Converter struct {
+	opts  Options
+	md    goldmark.Markdown
+	fonts string // Font face rules from opts.Fonts
+	page  *pageTemplate
+}
+
+// NewConverter prepares a Converter for opts
+func NewConverter(opts Options) (*Converter, error) {
+	md, err := newMarkdown(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newConverter(opts, md)
+}
+
+// newConverter creates a Converter around an existing goldmark instance
+func newConverter(opts Options, md goldmark.Markdown) (*Converter, error) {
+	// Collect custom font rules
+	fonts, err := fontCSS(opts.Fonts)
+	if err != nil {
+		return nil, err
 	}
 
-	htmlContent := buf.String()
+	return &Converter{
+		opts:  opts,
+		md:    md,
+		fonts: fonts,
+		page:  newPageTemplate(opts.Theme),
+	}, nil
+}
+
+// Options returns the options the Converter was created with
+func (c *Converter) Options() Options {
+	return c.opts
+}
+
+// document expands includes, parses source with the shared goldmark
+// instance and runs the configured filters
+func (c *Converter) document(ctx context.Context, source []byte) (*document, error) {
+	source, err := expandIncludes(source, c.opts.Includes)
+	if err != nil {
+		return nil, err
+	}
+	doc := parseWith(c.md, source)
+	if err := applyFilters(ctx, doc, c.opts, FilterFormatHTML); err != nil {
+		return nil, err
+	}
+	if err := renderDiagrams(ctx, doc, c.opts); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// ConvertToHTML converts markdown content to a full HTML page
+func (c *Converter) ConvertToHTML(markdown string) (string, error) {
+	return c.convertToHTML(context.Background(), markdown)
+}
+
+func (c *Converter) convertToHTML(ctx context.Context, markdown string) (string, error) {
+	doc, err := c.document(ctx, []byte(markdown))
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := c.writeHTML(&sb, doc); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// pageOptions carries per-document additions to the HTML wrapper
+type pageOptions struct {
+	lang         string // Escaped language of the html element
+	css          string // Appended to the theme styles
+	contentClass string // Extra classes on the content container
+	bodyPrefix   string // Markup inserted at the start of the body
+	title        string // Escaped document title
+	head         string // Extra tags for the head
+}
+
+// ConvertToHTML converts markdown content to HTML with Bootstrap styling
+func ConvertToHTML(markdown string, theme string) (string, error) {
+	return ConvertToHTMLWithOptions(markdown, Options{Theme: theme})
+}
+
+// ConvertToHTMLWithOptions converts markdown content to HTML using opts
+func ConvertToHTMLWithOptions(markdown string, opts Options) (string, error) {
+	c, err := NewConverter(opts)
+	if err != nil {
+		return "", err
+	}
+	return c.ConvertToHTML(markdown)
+}
+
+// renderHTML renders a parsed document into the full HTML page
+func renderHTML(doc *document, opts Options) (string, error) {
+	var sb strings.Builder
+	if err := writeHTML(&sb, doc, opts); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// writeHTML streams the full HTML page for a document parsed outside a
+// Converter to w
+func writeHTML(w io.Writer, doc *document, opts Options) error {
+	c, err := newConverter(opts, doc.md)
+	if err != nil {
+		return err
+	}
+	return c.writeHTML(w, doc)
+}
+
+// writePrintHTML is writeHTML for pages printed by wkhtmltopdf, whose
+// WebKit has no MathML layout of its own
+func writePrintHTML(w io.Writer, doc *document, opts Options) error {
+	c, err := newConverter(opts, doc.md)
+	if err != nil {
+		return err
+	}
+	css := codePrintCSS
+	if hasMath(doc.root) {
+		css += mathPrintCSS
+	}
+	// Printed pages cannot be clicked open
+	expandAdmonitions(doc.root)
+	return c.writePage(w, doc, css)
+}
+
+// writeHTML streams the full HTML page for a parsed document to w. The
+// markdown body is rendered straight into w, so an error can leave partial
+// output behind.
+func (c *Converter) writeHTML(w io.Writer, doc *document) error {
+	return c.writePage(w, doc, "")
+}
+
+// writePage is writeHTML with extra CSS rules for the page
+func (c *Converter) writePage(w io.Writer, doc *document, css string) error {
+	// Build the watermark overlay
+	wm, err := resolveWatermark(c.opts.Watermark, doc.meta, c.opts.Includes)
+	if err != nil {
+		return err
+	}
+	stamp, stampCSS, err := watermarkHTML(wm)
+	if err != nil {
+		return err
+	}
+
+	page := pageOptions{css: c.fonts + pageBreakCSS + stampCSS + documentCSS(doc.root, c.opts.Theme) + css, bodyPrefix: stamp}
+	info := resolveMetadata(c.opts.Metadata, doc)
+	page.title, page.head = metadataHTML(info)
+	page.lang = languageHTML(info)
+	if enhanced, copyButtons := codeBlockFeatures(doc, c.opts.CopyButtons); enhanced {
+		page.css += codeBlockCSS(c.opts.Theme)
+		if copyButtons {
+			page.head += codeCopyScript
+		}
+	}
+	if keepTogether(doc.meta) {
+		page.contentClass = classKeepTogether
+	}
 
 	// Wrap in HTML template with Bootstrap
-	return wrapInHTMLTemplate(htmlContent, theme), nil
+	head, tail := c.page.fill(page)
+	if _, err := io.WriteString(w, head); err != nil {
+		return fmt.Errorf("failed to write HTML: %w", err)
+	}
+
+	// Convert markdown to HTML
+	if err := doc.md.Renderer().Render(w, doc.source, doc.root); err != nil {
+		return fmt.Errorf("failed to convert markdown: %w", err)
+	}
+
+	if _, err := io.WriteString(w, tail); err != nil {
+		return fmt.Errorf("failed to write HTML: %w", err)
+	}
+	return nil
+}
+
+// documentCSS returns the styles for the optional node kinds that occur in
+// the document
+func documentCSS(root ast.Node, theme string) string {
+	kinds := make(map[ast.NodeKind]bool)
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			kinds[n.Kind()] = true
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var css string
+	if kinds[KindAdmonition] {
+		css += admonitionCSS(theme)
+	}
+	if kinds[east.KindFootnoteList] {
+		css += footnoteCSS
+	}
+	if kinds[east.KindDefinitionList] {
+		css += definitionListCSS
+	}
+	if kinds[KindDiagram] {
+		css += diagramCSS(theme)
+	}
+	if kinds[KindWikiLink] || kinds[KindBacklinks] {
+		css += wikiCSS
+	}
+	return css
+}
+
+// defaultTitle is the page title of documents without one
+const defaultTitle = "Markdown to HTML"
+
+// defaultLanguage is the page language of documents without one
+const defaultLanguage = "tr"
+
+// pageSlots is the number of per-document values in the page template:
+// language, title, head, css, body prefix, content class and content
+const pageSlots = 7
+
+// pageTemplate is the HTML wrapper of one theme split around its
+// per-document values, so filling it in only joins strings
+type pageTemplate struct {
+	text [pageSlots + 1]string
+}
+
+// slotMarker stands in for a per-document value when splitting the template
+func slotMarker(i int) string {
+	return fmt.Sprintf("\x00md2html-slot-%d\x00", i)
+}
+
+// newPageTemplate builds and splits the page template for theme
+func newPageTemplate(theme string) *pageTemplate {
+	page := pageOptions{
+		lang:         slotMarker(0),
+		title:        slotMarker(1),
+		head:         slotMarker(2),
+		css:          slotMarker(3),
+		bodyPrefix:   slotMarker(4),
+		contentClass: slotMarker(5),
+	}
+	rest := wrapInHTMLTemplate(slotMarker(6), theme, page)
+
+	t := &pageTemplate{}
+	for i := 0; i < pageSlots; i++ {
+		t.text[i], rest, _ = strings.Cut(rest, slotMarker(i))
+	}
+	t.text[pageSlots] = rest
+	return t
+}
+
+// fill returns the page before and after the document body
+func (t *pageTemplate) fill(page pageOptions) (string, string) {
+	if page.lang == "" {
+		page.lang = defaultLanguage
+	}
+	if page.title == "" {
+		page.title = defaultTitle
+	}
+
+	var head strings.Builder
+	for i, value := range []string{page.lang, page.title, page.head, page.css, page.bodyPrefix, page.contentClass} {
+		head.WriteString(t.text[i])
+		head.WriteString(value)
+	}
+	head.WriteString(t.text[pageSlots-1])
+	return head.String(), t.text[pageSlots]
 }
 
 // wrapInHTMLTemplate wraps the HTML content in a complete HTML document with Bootstrap
-func wrapInHTMLTemplate(content string, theme string) string {
+func wrapInHTMLTemplate(content string, theme string, page pageOptions) string {
 	var cssTheme string
 	var bodyClass string
 
 	switch theme {
-	case "dark":
+	case ThemeDark:
 		cssTheme = `
 		body {
 			background-color: #212529;
@@ -175,12 +515,19 @@ func wrapInHTMLTemplate(content string, theme string) string {
 		bodyClass = ""
 	}
 
+	if page.lang == "" {
+		page.lang = defaultLanguage
+	}
+	if page.title == "" {
+		page.title = defaultTitle
+	}
+
 	template := fmt.Sprintf(`<!DOCTYPE html>
-<html lang="tr">
+<html lang="%s">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Markdown to HTML</title>
+    <title>%s</title>%s
     
     <!-- Bootstrap CSS -->
     <link href="https://cdn.jsdelivr.net/npm/bootstrap@5.3.0/dist/css/bootstrap.min.css" rel="stylesheet">
@@ -288,6 +635,7 @@ func wrapInHTMLTemplate(content string, theme string) string {
     </style>
 </head>
 <body class="%s">
+    %s
     <!-- Navigation -->
     <nav class="navbar navbar-expand-lg navbar-light bg-light mb-4">
         <div class="container">
@@ -309,7 +657,7 @@ func wrapInHTMLTemplate(content string, theme string) string {
             <div class="col-lg-8">
                 <div class="card">
                     <div class="card-body">
-                        <div class="markdown-content">
+                        <div class="markdown-content %s">
                             %s
                         </div>
                     </div>
@@ -330,38 +678,12 @@ func wrapInHTMLTemplate(content string, theme string) string {
         Prism.highlightAll();
     </script>
 </body>
-</html>`, cssTheme, bodyClass, content)
+</html>`, page.lang, page.title, page.head, cssTheme+page.css, bodyClass, page.bodyPrefix, page.contentClass, content)
 
 	return template
 }
 
 // ConvertMultipleFiles converts multiple markdown files to HTML
 func ConvertMultipleFiles(inputFiles []string, outputDir string, theme string) error {
-	for _, inputFile := range inputFiles {
-		// Read markdown file
-		content, err := utils.ReadFile(inputFile)
-		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", inputFile, err)
-		}
-
-		// Convert to HTML
-		html, err := ConvertToHTML(content, theme)
-		if err != nil {
-			return fmt.Errorf("failed to convert %s: %w", inputFile, err)
-		}
-
-		// Generate output filename
-		base := filepath.Base(inputFile)
-		ext := filepath.Ext(base)
-		outputFile := filepath.Join(outputDir, strings.TrimSuffix(base, ext)+".html")
-
-		// Write output file
-		if err := utils.WriteFile(outputFile, html); err != nil {
-			return fmt.Errorf("failed to write %s: %w", outputFile, err)
-		}
-
-		fmt.Printf("Converted %s to %s\n", inputFile, outputFile)
-	}
-
-	return nil
+	return ConvertMultipleFilesContext(context.Background(), inputFiles, outputDir, Options{Theme: theme})
 }
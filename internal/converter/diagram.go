@@ -0,0 +1,253 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// EnvPlantUMLJar names a local PlantUML jar used instead of a plantuml
+// executable on the PATH
+const EnvPlantUMLJar = "PLANTUML_JAR"
+
+// DefaultDiagrams returns the built-in diagram commands by fence language:
+// Graphviz dot, and PlantUML from $PLANTUML_JAR or the PATH
+func DefaultDiagrams() map[string]string {
+	dot := "dot -Tsvg"
+	plantuml := "plantuml -tsvg -pipe"
+	if jar := os.Getenv(EnvPlantUMLJar); jar != "" {
+		plantuml = "java -Djava.awt.headless=true -jar " + jar + " -tsvg -pipe"
+	}
+	return map[string]string{
+		"dot":      dot,
+		"graphviz": dot,
+		"plantuml": plantuml,
+		"puml":     plantuml,
+	}
+}
+
+// DefaultDiagramCache returns the directory rendered diagrams are cached in
+// when Options.DiagramCache is empty
+func DefaultDiagramCache() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "markdown-to-html", "diagrams")
+}
+
+// KindDiagram is the node kind of a rendered diagram fence
+var KindDiagram = ast.NewNodeKind("Diagram")
+
+// Diagram is a diagram fence rendered to SVG. Its lines still hold the
+// diagram source.
+type Diagram struct {
+	ast.BaseBlock
+
+	// Language is the fence language, e.g. "dot"
+	Language string
+
+	// SVG is the rendered svg element
+	SVG []byte
+}
+
+// Kind implements ast.Node
+func (n *Diagram) Kind() ast.NodeKind {
+	return KindDiagram
+}
+
+// IsRaw implements ast.Node
+func (n *Diagram) IsRaw() bool {
+	return true
+}
+
+// Dump implements ast.Node
+func (n *Diagram) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Language": n.Language}, nil)
+}
+
+// renderDiagrams replaces the diagram and chart fences of doc with their
+// SVG. A fence whose command is missing or fails, or whose chart data is
+// invalid, stays a code block; only a done ctx stops the conversion.
+func renderDiagrams(ctx context.Context, doc *document, opts Options) error {
+	commands := opts.Diagrams
+	var fences []*ast.FencedCodeBlock
+	_ = ast.Walk(doc.root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if fence, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			language := string(fence.Language(doc.source))
+			if language == chartLanguage || commands[language] != "" {
+				fences = append(fences, fence)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	cache := opts.DiagramCache
+	if cache == "" {
+		cache = DefaultDiagramCache()
+	}
+	for _, fence := range fences {
+		language := string(fence.Language(doc.source))
+		var svg []byte
+		if language == chartLanguage {
+			spec, err := parseChart(blockText(fence, doc.source), fenceArgument(fence, doc.source))
+			if err != nil {
+				continue
+			}
+			svg = chartSVG(spec, opts.Theme)
+		} else {
+			var err error
+			svg, err = diagramSVG(ctx, commands[language], blockText(fence, doc.source), cache)
+			if err != nil {
+				if ctx.Err() != nil {
+					return fmt.Errorf("diagram %q: %w", language, ctx.Err())
+				}
+				continue
+			}
+		}
+
+		diagram := &Diagram{Language: language, SVG: svg}
+		diagram.SetLines(fence.Lines())
+		fence.Parent().ReplaceChild(fence.Parent(), fence, diagram)
+	}
+	return nil
+}
+
+// fenceArgument returns the word after the language of a fence's info
+// string, as "pie" in ```chart pie
+func fenceArgument(fence *ast.FencedCodeBlock, source []byte) string {
+	if fence.Info == nil {
+		return ""
+	}
+	fields := strings.Fields(string(fence.Info.Segment.Value(source)))
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[1]
+}
+
+// diagramSVG renders source with command, reusing a cached result for the
+// same command and source
+func diagramSVG(ctx context.Context, command string, source []byte, cache string) ([]byte, error) {
+	sum := sha256.Sum256(append([]byte(command+"\x00"), source...))
+	path := filepath.Join(cache, hex.EncodeToString(sum[:])+".svg")
+	if svg, err := os.ReadFile(path); err == nil {
+		return svg, nil
+	}
+
+	svg, err := runDiagram(ctx, command, source)
+	if err != nil {
+		return nil, err
+	}
+
+	// Caching is best effort; write a temporary file first so concurrent
+	// conversions never read a partial diagram
+	if err := os.MkdirAll(cache, 0755); err == nil {
+		if tmp, err := os.CreateTemp(cache, "diagram-*.tmp"); err == nil {
+			_, werr := tmp.Write(svg)
+			cerr := tmp.Close()
+			if werr != nil || cerr != nil || os.Rename(tmp.Name(), path) != nil {
+				os.Remove(tmp.Name())
+			}
+		}
+	}
+	return svg, nil
+}
+
+// runDiagram runs a diagram command with source on stdin and returns the
+// svg element it writes to stdout. The command is split on spaces like
+// filter commands.
+func runDiagram(ctx context.Context, command string, source []byte) ([]byte, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty diagram command")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(source)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("diagram command %q failed: %w: %s", command, err, msg)
+		}
+		return nil, fmt.Errorf("diagram command %q failed: %w", command, err)
+	}
+
+	// Drop the XML declaration, doctype and comments before the svg element
+	svg := stdout.Bytes()
+	start := bytes.Index(svg, []byte("<svg"))
+	if start < 0 {
+		return nil, fmt.Errorf("diagram command %q did not write SVG", command)
+	}
+	return bytes.TrimSpace(svg[start:]), nil
+}
+
+// diagramHTMLRenderer writes diagrams as inline SVG figures
+type diagramHTMLRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *diagramHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindDiagram, r.render)
+}
+
+func (r *diagramHTMLRenderer) render(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	diagram := n.(*Diagram)
+	_, _ = fmt.Fprintf(w, "<figure class=\"diagram diagram-%s\">\n", util.EscapeHTML([]byte(diagram.Language)))
+	_, _ = w.Write(diagram.SVG)
+	_, _ = w.WriteString("\n</figure>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+// diagramExtension renders diagram nodes to HTML
+type diagramExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *diagramExtension) Extend(m goldmark.Markdown) {
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&diagramHTMLRenderer{}, 500),
+	))
+}
+
+// diagramCSS scales diagrams to the page; on the dark theme they keep a
+// light backdrop since diagram tools draw black on white. Charts are drawn
+// in theme colours and need none.
+func diagramCSS(theme string) string {
+	css := `
+		.diagram {
+			margin: 1rem 0;
+			text-align: center;
+			page-break-inside: avoid;
+		}
+		.diagram svg {
+			max-width: 100%;
+			height: auto;
+		}`
+	if theme == ThemeDark {
+		css += `
+		.diagram:not(.diagram-chart) {
+			background: #ffffff;
+			border-radius: 0.375rem;
+			padding: 0.75rem;
+		}`
+	}
+	return css
+}
@@ -0,0 +1,178 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables that override executable discovery. WKHTMLTOPDF_BIN
+// names the binary itself; WKHTMLTOPDF_PATH names the directory containing it
+// and is also honoured by go-wkhtmltopdf.
+const (
+	EnvWkhtmltopdfBin = "WKHTMLTOPDF_BIN"
+	EnvWkhtmltopdfDir = "WKHTMLTOPDF_PATH"
+)
+
+// ExecutableInfo describes a discovered wkhtmltopdf-family executable
+type ExecutableInfo struct {
+	Path      string // Absolute path to the executable
+	Source    string // How it was found: flag, env, PATH or default location
+	Version   string // Version number such as "0.12.6"
+	PatchedQt bool   // Built against the patched Qt required by advanced options
+}
+
+var (
+	overrideMu   sync.RWMutex
+	overridePath string
+)
+
+// SetWkhtmltopdfPath overrides discovery with an explicit wkhtmltopdf binary.
+// wkhtmltoimage is looked up next to it.
+func SetWkhtmltopdfPath(path string) {
+	overrideMu.Lock()
+	defer overrideMu.Unlock()
+	overridePath = path
+}
+
+// FindWkhtmltopdf returns the path of the wkhtmltopdf executable
+func FindWkhtmltopdf() (string, error) {
+	path, _, err := findExecutable("wkhtmltopdf")
+	return path, err
+}
+
+// IsWkhtmltopdfInstalled checks if wkhtmltopdf is installed
+func IsWkhtmltopdfInstalled() bool {
+	_, err := FindWkhtmltopdf()
+	return err == nil
+}
+
+// DetectWkhtmltopdf locates wkhtmltopdf and reports its version and capabilities
+func DetectWkhtmltopdf() (*ExecutableInfo, error) {
+	return detectExecutable("wkhtmltopdf")
+}
+
+// findExecutable looks up name in order: explicit override, environment
+// variables, PATH, then well-known install locations
+func findExecutable(name string) (string, string, error) {
+	overrideMu.RLock()
+	override := overridePath
+	overrideMu.RUnlock()
+
+	// Sibling tools live next to the configured wkhtmltopdf binary
+	if override != "" {
+		path := siblingExecutable(override, name)
+		if isExecutableFile(path) {
+			return path, "flag", nil
+		}
+		return "", "", fmt.Errorf("%s not found at %s", name, path)
+	}
+
+	if bin := os.Getenv(EnvWkhtmltopdfBin); bin != "" {
+		path := siblingExecutable(bin, name)
+		if isExecutableFile(path) {
+			return path, "env " + EnvWkhtmltopdfBin, nil
+		}
+		return "", "", fmt.Errorf("%s not found at %s (from %s)", name, path, EnvWkhtmltopdfBin)
+	}
+
+	if dir := os.Getenv(EnvWkhtmltopdfDir); dir != "" {
+		path := filepath.Join(dir, executableName(name))
+		if isExecutableFile(path) {
+			return path, "env " + EnvWkhtmltopdfDir, nil
+		}
+	}
+
+	if path, err := exec.LookPath(name); err == nil {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return path, "PATH", nil
+	}
+
+	for _, dir := range defaultInstallDirs() {
+		path := filepath.Join(dir, executableName(name))
+		if isExecutableFile(path) {
+			return path, "default location", nil
+		}
+	}
+
+	return "", "", fmt.Errorf("%s not found", name)
+}
+
+// siblingExecutable resolves name relative to a configured wkhtmltopdf
+// binary: the binary itself, or a tool in the same directory
+func siblingExecutable(bin string, name string) string {
+	if name == "wkhtmltopdf" {
+		return bin
+	}
+	return filepath.Join(filepath.Dir(bin), executableName(name))
+}
+
+func executableName(name string) string {
+	if runtime.GOOS == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
+
+func isExecutableFile(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode()&0111 != 0
+}
+
+// defaultInstallDirs lists the directories installers commonly use
+func defaultInstallDirs() []string {
+	if runtime.GOOS == "windows" {
+		return []string{
+			`C:\Program Files\wkhtmltopdf\bin`,
+			`C:\Program Files (x86)\wkhtmltopdf\bin`,
+		}
+	}
+	return []string{
+		"/usr/local/bin",
+		"/usr/bin",
+		"/opt/homebrew/bin",
+		"/opt/wkhtmltopdf/bin",
+		"/usr/local/opt/wkhtmltopdf/bin",
+	}
+}
+
+var versionPattern = regexp.MustCompile(`(\d+\.\d+\.\d+)`)
+
+// detectExecutable locates name and runs it with --version
+func detectExecutable(name string) (*ExecutableInfo, error) {
+	path, source, err := findExecutable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s --version: %w", path, err)
+	}
+
+	info := &ExecutableInfo{
+		Path:      path,
+		Source:    source,
+		PatchedQt: strings.Contains(strings.ToLower(string(out)), "patched qt"),
+	}
+	if match := versionPattern.FindString(string(out)); match != "" {
+		info.Version = match
+	}
+
+	return info, nil
+}
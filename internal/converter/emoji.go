@@ -0,0 +1,176 @@
+package converter
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// emojiShortcodes maps GitHub emoji shortcodes to their Unicode characters
+var emojiShortcodes = map[string]string{
+	// Smileys and people
+	"smile": "😄", "smiley": "😃", "grinning": "😀", "grin": "😁", "laughing": "😆", "satisfied": "😆",
+	"sweat_smile": "😅", "joy": "😂", "rofl": "🤣", "relaxed": "☺️", "blush": "😊", "innocent": "😇",
+	"slightly_smiling_face": "🙂", "upside_down_face": "🙃", "wink": "😉", "relieved": "😌",
+	"heart_eyes": "😍", "star_struck": "🤩", "kissing_heart": "😘", "yum": "😋", "stuck_out_tongue": "😛",
+	"stuck_out_tongue_winking_eye": "😜", "zany_face": "🤪", "nerd_face": "🤓", "sunglasses": "😎",
+	"partying_face": "🥳", "smirk": "😏", "unamused": "😒", "disappointed": "😞", "pensive": "😔",
+	"worried": "😟", "confused": "😕", "slightly_frowning_face": "🙁", "persevere": "😣",
+	"confounded": "😖", "tired_face": "😫", "weary": "😩", "pleading_face": "🥺", "cry": "😢", "sob": "😭",
+	"triumph": "😤", "angry": "😠", "rage": "😡", "exploding_head": "🤯", "flushed": "😳",
+	"scream": "😱", "fearful": "😨", "cold_sweat": "😰", "hugs": "🤗", "thinking": "🤔",
+	"shushing_face": "🤫", "lying_face": "🤥", "no_mouth": "😶", "neutral_face": "😐",
+	"expressionless": "😑", "grimacing": "😬", "roll_eyes": "🙄", "hushed": "😯", "open_mouth": "😮",
+	"astonished": "😲", "sleeping": "😴", "sleepy": "😪", "dizzy_face": "😵", "mask": "😷",
+	"face_with_thermometer": "🤒", "nauseated_face": "🤢", "sneezing_face": "🤧", "cowboy_hat_face": "🤠",
+	"clown_face": "🤡", "smiling_imp": "😈", "skull": "💀", "ghost": "👻", "alien": "👽", "robot": "🤖",
+	"poop": "💩", "hankey": "💩", "see_no_evil": "🙈", "hear_no_evil": "🙉", "speak_no_evil": "🙊",
+
+	// Hands and gestures
+	"+1": "👍", "thumbsup": "👍", "-1": "👎", "thumbsdown": "👎", "ok_hand": "👌", "wave": "👋",
+	"clap": "👏", "raised_hands": "🙌", "pray": "🙏", "handshake": "🤝", "muscle": "💪",
+	"point_up": "☝️", "point_down": "👇", "point_left": "👈", "point_right": "👉", "fist": "✊",
+	"facepunch": "👊", "punch": "👊", "v": "✌️", "crossed_fingers": "🤞", "metal": "🤘",
+	"call_me_hand": "🤙", "raised_hand": "✋", "hand": "✋", "open_hands": "👐", "writing_hand": "✍️",
+	"eyes": "👀", "eye": "👁️", "brain": "🧠", "tada": "🎉", "confetti_ball": "🎊",
+	"bow": "🙇", "facepalm": "🤦", "shrug": "🤷", "man_technologist": "👨‍💻", "woman_technologist": "👩‍💻",
+	"technologist": "🧑‍💻", "construction_worker": "👷", "detective": "🕵️", "ninja": "🥷",
+
+	// Hearts and symbols
+	"heart": "❤️", "orange_heart": "🧡", "yellow_heart": "💛", "green_heart": "💚", "blue_heart": "💙",
+	"purple_heart": "💜", "black_heart": "🖤", "white_heart": "🤍", "broken_heart": "💔",
+	"sparkling_heart": "💖", "heartpulse": "💗", "two_hearts": "💕", "100": "💯", "boom": "💥",
+	"collision": "💥", "anger": "💢", "dizzy": "💫", "sweat_drops": "💦", "zzz": "💤",
+	"speech_balloon": "💬", "thought_balloon": "💭", "star": "⭐", "star2": "🌟", "sparkles": "✨",
+	"zap": "⚡", "fire": "🔥", "snowflake": "❄️", "rainbow": "🌈", "sunny": "☀️", "cloud": "☁️",
+	"umbrella": "☔", "droplet": "💧", "ocean": "🌊", "earth_africa": "🌍", "earth_americas": "🌎",
+	"earth_asia": "🌏", "globe_with_meridians": "🌐", "crescent_moon": "🌙", "full_moon": "🌕",
+
+	// Status and marks
+	"white_check_mark": "✅", "heavy_check_mark": "✔️", "ballot_box_with_check": "☑️", "x": "❌",
+	"negative_squared_cross_mark": "❎", "heavy_multiplication_x": "✖️", "heavy_plus_sign": "➕",
+	"heavy_minus_sign": "➖", "warning": "⚠️", "no_entry": "⛔", "no_entry_sign": "🚫", "stop_sign": "🛑",
+	"exclamation": "❗", "heavy_exclamation_mark": "❗", "grey_exclamation": "❕", "question": "❓",
+	"grey_question": "❔", "bangbang": "‼️", "interrobang": "⁉️", "information_source": "ℹ️",
+	"red_circle": "🔴", "orange_circle": "🟠", "yellow_circle": "🟡", "green_circle": "🟢",
+	"large_blue_circle": "🔵", "blue_circle": "🔵", "purple_circle": "🟣", "black_circle": "⚫",
+	"white_circle": "⚪", "red_square": "🟥", "green_square": "🟩", "yellow_square": "🟨",
+	"large_orange_diamond": "🔶", "large_blue_diamond": "🔷", "small_red_triangle": "🔺",
+	"small_red_triangle_down": "🔻", "arrow_up": "⬆️", "arrow_down": "⬇️", "arrow_left": "⬅️",
+	"arrow_right": "➡️", "arrow_up_down": "↕️", "left_right_arrow": "↔️", "arrows_counterclockwise": "🔄",
+	"repeat": "🔁", "leftwards_arrow_with_hook": "↩️", "arrow_right_hook": "↪️", "heavy_dollar_sign": "💲",
+	"copyright": "©️", "registered": "®️", "tm": "™️", "new": "🆕", "free": "🆓", "up": "🆙", "cool": "🆒",
+	"ok": "🆗", "sos": "🆘", "soon": "🔜", "top": "🔝", "back": "🔙", "end": "🔚", "on": "🔛",
+	"recycle": "♻️", "beginner": "🔰", "trident": "🔱", "o": "⭕", "hash": "#️⃣", "asterisk": "*️⃣",
+	"zero": "0️⃣", "one": "1️⃣", "two": "2️⃣", "three": "3️⃣", "four": "4️⃣", "five": "5️⃣", "six": "6️⃣",
+	"seven": "7️⃣", "eight": "8️⃣", "nine": "9️⃣", "keycap_ten": "🔟", "pushpin": "📌",
+	"round_pushpin": "📍", "triangular_flag_on_post": "🚩", "checkered_flag": "🏁", "white_flag": "🏳️",
+	"black_flag": "🏴", "rainbow_flag": "🏳️‍🌈", "tr": "🇹🇷", "de": "🇩🇪", "fr": "🇫🇷", "gb": "🇬🇧",
+	"uk": "🇬🇧", "us": "🇺🇸", "it": "🇮🇹", "es": "🇪🇸", "jp": "🇯🇵", "cn": "🇨🇳", "eu": "🇪🇺",
+
+	// Objects and tools
+	"rocket": "🚀", "airplane": "✈️", "car": "🚗", "red_car": "🚗", "bike": "🚲", "ship": "🚢",
+	"construction": "🚧", "rotating_light": "🚨", "traffic_light": "🚥", "vertical_traffic_light": "🚦",
+	"bulb": "💡", "flashlight": "🔦", "battery": "🔋", "electric_plug": "🔌", "computer": "💻",
+	"desktop_computer": "🖥️", "keyboard": "⌨️", "computer_mouse": "🖱️", "printer": "🖨️",
+	"iphone": "📱", "telephone": "☎️", "phone": "☎️", "fax": "📠", "tv": "📺", "camera": "📷",
+	"video_camera": "📹", "movie_camera": "🎥", "floppy_disk": "💾", "cd": "💿", "dvd": "📀",
+	"minidisc": "💽", "abacus": "🧮", "satellite": "📡", "wrench": "🔧", "hammer": "🔨",
+	"hammer_and_wrench": "🛠️", "nut_and_bolt": "🔩", "gear": "⚙️", "link": "🔗", "paperclip": "📎",
+	"scissors": "✂️", "lock": "🔒", "unlock": "🔓", "key": "🔑", "old_key": "🗝️", "shield": "🛡️",
+	"microscope": "🔬", "telescope": "🔭", "test_tube": "🧪", "dna": "🧬", "pill": "💊", "syringe": "💉",
+	"mag": "🔍", "mag_right": "🔎", "bell": "🔔", "no_bell": "🔕", "loudspeaker": "📢", "mega": "📣",
+	"hourglass": "⌛", "hourglass_flowing_sand": "⏳", "watch": "⌚", "alarm_clock": "⏰", "stopwatch": "⏱️",
+	"timer_clock": "⏲️", "calendar": "📆", "date": "📅", "spiral_calendar": "🗓️", "clipboard": "📋",
+	"memo": "📝", "pencil": "📝", "pencil2": "✏️", "pen": "🖊️", "black_nib": "✒️", "crayon": "🖍️",
+	"book": "📖", "open_book": "📖", "books": "📚", "notebook": "📓", "ledger": "📒", "bookmark": "🔖",
+	"label": "🏷️", "page_facing_up": "📄", "page_with_curl": "📃", "scroll": "📜", "newspaper": "📰",
+	"bookmark_tabs": "📑", "bar_chart": "📊", "chart_with_upwards_trend": "📈",
+	"chart_with_downwards_trend": "📉", "card_index": "📇", "file_folder": "📁", "open_file_folder": "📂",
+	"card_index_dividers": "🗂️", "file_cabinet": "🗄️", "wastebasket": "🗑️", "package": "📦",
+	"mailbox": "📫", "inbox_tray": "📥", "outbox_tray": "📤", "email": "📧", "e-mail": "📧",
+	"envelope": "✉️", "incoming_envelope": "📨", "postbox": "📮", "gift": "🎁", "trophy": "🏆",
+	"medal_sports": "🏅", "1st_place_medal": "🥇", "2nd_place_medal": "🥈", "3rd_place_medal": "🥉",
+	"dart": "🎯", "game_die": "🎲", "jigsaw": "🧩", "video_game": "🎮", "art": "🎨", "musical_note": "🎵",
+	"notes": "🎶", "microphone": "🎤", "headphones": "🎧", "balloon": "🎈", "ribbon": "🎀",
+	"moneybag": "💰", "dollar": "💵", "euro": "💶", "credit_card": "💳", "gem": "💎", "bank": "🏦",
+	"house": "🏠", "office": "🏢", "factory": "🏭", "hospital": "🏥", "school": "🏫", "tent": "⛺",
+	"world_map": "🗺️", "compass": "🧭", "anchor": "⚓", "magnet": "🧲", "bomb": "💣", "crystal_ball": "🔮",
+	"broom": "🧹", "basket": "🧺", "soap": "🧼", "sponge": "🧽", "toolbox": "🧰", "ladder": "🪜",
+
+	// Nature, food and animals
+	"seedling": "🌱", "herb": "🌿", "four_leaf_clover": "🍀", "evergreen_tree": "🌲", "deciduous_tree": "🌳",
+	"palm_tree": "🌴", "cactus": "🌵", "tulip": "🌷", "rose": "🌹", "sunflower": "🌻", "blossom": "🌼",
+	"cherry_blossom": "🌸", "fallen_leaf": "🍂", "maple_leaf": "🍁", "mushroom": "🍄", "apple": "🍎",
+	"green_apple": "🍏", "lemon": "🍋", "banana": "🍌", "watermelon": "🍉", "grapes": "🍇",
+	"strawberry": "🍓", "cherries": "🍒", "peach": "🍑", "tomato": "🍅", "avocado": "🥑", "carrot": "🥕",
+	"corn": "🌽", "hot_pepper": "🌶️", "bread": "🍞", "cheese": "🧀", "hamburger": "🍔", "fries": "🍟",
+	"pizza": "🍕", "hotdog": "🌭", "taco": "🌮", "burrito": "🌯", "popcorn": "🍿", "cookie": "🍪",
+	"cake": "🍰", "birthday": "🎂", "doughnut": "🍩", "chocolate_bar": "🍫", "candy": "🍬",
+	"lollipop": "🍭", "coffee": "☕", "tea": "🍵", "beer": "🍺", "beers": "🍻", "wine_glass": "🍷",
+	"cocktail": "🍸", "tropical_drink": "🍹", "champagne": "🍾", "cup_with_straw": "🥤",
+	"dog": "🐶", "cat": "🐱", "mouse": "🐭", "hamster": "🐹", "rabbit": "🐰", "fox_face": "🦊",
+	"bear": "🐻", "panda_face": "🐼", "koala": "🐨", "tiger": "🐯", "lion": "🦁", "cow": "🐮", "pig": "🐷",
+	"frog": "🐸", "monkey_face": "🐵", "chicken": "🐔", "penguin": "🐧", "bird": "🐦", "baby_chick": "🐤",
+	"owl": "🦉", "eagle": "🦅", "duck": "🦆", "bat": "🦇", "wolf": "🐺", "horse": "🐴", "unicorn": "🦄",
+	"bee": "🐝", "honeybee": "🐝", "bug": "🐛", "butterfly": "🦋", "snail": "🐌", "beetle": "🐞",
+	"lady_beetle": "🐞", "ant": "🐜", "spider": "🕷️", "turtle": "🐢", "snake": "🐍", "lizard": "🦎",
+	"t-rex": "🦖", "sauropod": "🦕", "octopus": "🐙", "crab": "🦀", "fish": "🐟", "tropical_fish": "🐠",
+	"dolphin": "🐬", "whale": "🐳", "shark": "🦈", "elephant": "🐘", "camel": "🐫", "giraffe": "🦒",
+	"paw_prints": "🐾", "feet": "🐾", "dragon": "🐉",
+}
+
+// emojiParser replaces :shortcode: with its emoji. Shortcodes must stand
+// apart from letters and digits, so times such as 10:100: stay text.
+type emojiParser struct{}
+
+// Trigger implements parser.InlineParser
+func (p *emojiParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+// Parse implements parser.InlineParser
+func (p *emojiParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if prev := block.PrecendingCharacter(); isWordRune(prev) {
+		return nil
+	}
+	line, _ := block.PeekLine()
+	end := 1
+	for end < len(line) && isShortcodeByte(line[end]) {
+		end++
+	}
+	if end == 1 || end >= len(line) || line[end] != ':' {
+		return nil
+	}
+	if end+1 < len(line) && isWordRune(rune(line[end+1])) {
+		return nil
+	}
+
+	emoji, ok := emojiShortcodes[string(line[1:end])]
+	if !ok {
+		return nil
+	}
+	block.Advance(end + 1)
+	return ast.NewString([]byte(emoji))
+}
+
+func isShortcodeByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '+' || c == '-'
+}
+
+// isWordRune reports whether r is an ASCII letter or digit
+func isWordRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+// emojiExtension converts emoji shortcodes to Unicode
+type emojiExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *emojiExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		util.Prioritized(&emojiParser{}, 150),
+	))
+}
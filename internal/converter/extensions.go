@@ -0,0 +1,182 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// Built-in extensions that can be enabled by name
+const (
+	ExtFootnote       = "footnote"
+	ExtDefinitionList = "deflist"
+	ExtTypographer    = "typographer"
+	ExtCJK            = "cjk"
+	ExtMath           = "math"
+	ExtEmoji          = "emoji"
+)
+
+var (
+	extensionsMu    sync.RWMutex
+	namedExtensions = map[string]goldmark.Extender{
+		ExtFootnote:       extension.Footnote,
+		ExtDefinitionList: extension.DefinitionList,
+		ExtTypographer:    typographer,
+		ExtCJK:            extension.CJK,
+		ExtMath:           &mathExtension{},
+		ExtEmoji:          &emojiExtension{},
+	}
+)
+
+// footnoteCSS sets footnotes apart as a smaller section at the end; printed
+// pages drop the links back to the text
+const footnoteCSS = `
+		.markdown-content .footnote-ref {
+			text-decoration: none;
+			padding: 0 0.1em;
+		}
+		.markdown-content .footnotes {
+			margin-top: 2rem;
+			font-size: 0.875em;
+			opacity: 0.85;
+		}
+		.markdown-content .footnotes hr {
+			width: 33%;
+			margin: 1rem 0;
+		}
+		.markdown-content .footnotes li {
+			margin-bottom: 0.25rem;
+		}
+		.markdown-content .footnotes p {
+			margin-bottom: 0.25rem;
+		}
+		.markdown-content .footnote-backref {
+			text-decoration: none;
+			margin-left: 0.25em;
+		}
+		@media print {
+			.markdown-content .footnote-backref {
+				display: none;
+			}
+			.markdown-content .footnotes {
+				page-break-inside: avoid;
+				break-inside: avoid;
+			}
+		}`
+
+// definitionListCSS indents definitions under their terms
+const definitionListCSS = `
+		.markdown-content dt {
+			margin-top: 0.75rem;
+		}
+		.markdown-content dd {
+			margin-left: 1.5rem;
+			margin-bottom: 0.5rem;
+		}`
+
+// RegisterExtension makes ext available under name for Options.Extensions
+// and the --ext flag. Registering an existing name replaces it.
+func RegisterExtension(name string, ext goldmark.Extender) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	namedExtensions[strings.ToLower(name)] = ext
+}
+
+// ExtensionNames returns the sorted names of all registered extensions
+func ExtensionNames() []string {
+	extensionsMu.RLock()
+	defer extensionsMu.RUnlock()
+
+	names := make([]string, 0, len(namedExtensions))
+	for name := range namedExtensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseExtensions parses a comma separated list of extension names such as
+// "footnote,deflist" and checks that each one is registered
+func ParseExtensions(s string) ([]string, error) {
+	var names []string
+	for _, part := range strings.Split(s, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		if _, err := namedExtenders([]string{name}); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// namedExtenders resolves extension names to their goldmark extensions
+func namedExtenders(names []string) ([]goldmark.Extender, error) {
+	extensionsMu.RLock()
+	defer extensionsMu.RUnlock()
+
+	extenders := make([]goldmark.Extender, 0, len(names))
+	for _, name := range names {
+		ext, ok := namedExtensions[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown extension %q (available: %s)", name, strings.Join(sortedKeys(namedExtensions), ", "))
+		}
+		extenders = append(extenders, ext)
+	}
+	return extenders, nil
+}
+
+func sortedKeys(m map[string]goldmark.Extender) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ExtenderFunc adapts a function to goldmark.Extender
+type ExtenderFunc func(m goldmark.Markdown)
+
+// Extend implements goldmark.Extender
+func (f ExtenderFunc) Extend(m goldmark.Markdown) {
+	f(m)
+}
+
+// WithParserOptions returns an extension that applies goldmark parser options
+func WithParserOptions(opts ...parser.Option) goldmark.Extender {
+	return ExtenderFunc(func(m goldmark.Markdown) {
+		m.Parser().AddOptions(opts...)
+	})
+}
+
+// WithRendererOptions returns an extension that applies goldmark HTML
+// renderer options
+func WithRendererOptions(opts ...renderer.Option) goldmark.Extender {
+	return ExtenderFunc(func(m goldmark.Markdown) {
+		m.Renderer().AddOptions(opts...)
+	})
+}
+
+// WithASTTransformer returns an extension that runs t on every parsed
+// document. Transformers with lower priorities run first; the built-in page
+// break transformer uses 500.
+func WithASTTransformer(t parser.ASTTransformer, priority int) goldmark.Extender {
+	return WithParserOptions(parser.WithASTTransformers(util.Prioritized(t, priority)))
+}
+
+// WithNodeRenderer returns an extension that renders nodes to HTML with r.
+// Renderers with lower priorities win when several register the same node
+// kind; the default goldmark renderer uses 1000.
+func WithNodeRenderer(r renderer.NodeRenderer, priority int) goldmark.Extender {
+	return WithRendererOptions(renderer.WithNodeRenderers(util.Prioritized(r, priority)))
+}
@@ -0,0 +1,619 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// FilterASTVersion is the version of the JSON document exchanged with
+// filters. It changes when existing node fields change meaning.
+const FilterASTVersion = 1
+
+// Output formats passed to filters as their only argument
+const (
+	FilterFormatHTML = "html"
+	FilterFormatPDF  = "pdf"
+)
+
+// filterDocument is the JSON form of a parsed document
+type filterDocument struct {
+	Version int                    `json:"version"`
+	Format  string                 `json:"format"`
+	Meta    map[string]interface{} `json:"meta"`
+	Blocks  []*filterNode          `json:"blocks"`
+}
+
+// filterNode is the JSON form of one AST node. Only the fields that apply to
+// Type are set. Nodes the format does not describe, such as footnotes or
+// nodes from custom extensions, carry an Opaque reference instead: their
+// children can be edited, moved or removed, but the node itself passes
+// through unchanged.
+type filterNode struct {
+	Type       string            `json:"type"`
+	Opaque     int               `json:"opaque,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Children   []*filterNode     `json:"children,omitempty"`
+
+	Text        string   `json:"text,omitempty"`        // Text, String, Code, CodeBlock, HTMLBlock, RawHTML, AutoLink label
+	Level       int      `json:"level,omitempty"`       // Heading, Emphasis
+	Destination string   `json:"destination,omitempty"` // Link, Image, AutoLink
+	Title       string   `json:"title,omitempty"`       // Link, Image
+	Info        string   `json:"info,omitempty"`        // CodeBlock info string, e.g. "go"
+	Marker      string   `json:"marker,omitempty"`      // List: "-", "*", "+", "." or ")"
+	Start       int      `json:"start,omitempty"`       // Ordered List
+	Tight       bool     `json:"tight,omitempty"`       // List
+	Checked     bool     `json:"checked,omitempty"`     // TaskCheckBox
+	Alignment   string   `json:"alignment,omitempty"`   // TableCell: left, right, center or none
+	SoftBreak   bool     `json:"softBreak,omitempty"`   // Text
+	HardBreak   bool     `json:"hardBreak,omitempty"`   // Text
+	Raw         bool     `json:"raw,omitempty"`         // Text, String: backslash escapes and entities are kept as written
+	HTML        bool     `json:"html,omitempty"`        // String: Text is HTML written verbatim
+	Email       bool     `json:"email,omitempty"`       // AutoLink
+	Fenced      bool     `json:"fenced,omitempty"`      // CodeBlock
+	Alignments  []string `json:"alignments,omitempty"`  // Table
+}
+
+// Node types understood by filters besides opaque ones
+const (
+	filterParagraph   = "Paragraph"
+	filterTextBlock   = "TextBlock"
+	filterHeading     = "Heading"
+	filterRule        = "ThematicBreak"
+	filterCodeBlock   = "CodeBlock"
+	filterBlockquote  = "Blockquote"
+	filterList        = "List"
+	filterListItem    = "ListItem"
+	filterHTMLBlock   = "HTMLBlock"
+	filterText        = "Text"
+	filterString      = "String"
+	filterCode        = "Code"
+	filterEmphasis    = "Emphasis"
+	filterLink        = "Link"
+	filterImage       = "Image"
+	filterAutoLink    = "AutoLink"
+	filterRawHTML     = "RawHTML"
+	filterTable       = "Table"
+	filterTableHeader = "TableHeader"
+	filterTableRow    = "TableRow"
+	filterTableCell   = "TableCell"
+	filterStrike      = "Strikethrough"
+	filterCheckBox    = "TaskCheckBox"
+)
+
+// applyFilters pipes doc through the filter commands from opts, in order,
+// and replaces its tree and front matter with the result. Each filter reads
+// the JSON document on stdin, receives format as its argument and writes
+// the modified document to stdout.
+func applyFilters(ctx context.Context, doc *document, opts Options, format string) error {
+	if len(opts.Filters) == 0 {
+		return nil
+	}
+
+	enc := &filterEncoder{source: doc.source, opaque: make(map[int]ast.Node)}
+	data, err := json.Marshal(enc.document(doc, format))
+	if err != nil {
+		return fmt.Errorf("failed to encode document for filters: %w", err)
+	}
+
+	for _, command := range opts.Filters {
+		data, err = runFilter(ctx, command, format, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	var filtered filterDocument
+	if err := json.Unmarshal(data, &filtered); err != nil {
+		return fmt.Errorf("failed to read filtered document: %w", err)
+	}
+
+	dec := &filterDecoder{
+		source: append([]byte(nil), doc.source...),
+		opaque: enc.opaque,
+		used:   make(map[int]bool),
+	}
+	root := ast.NewDocument()
+	for _, block := range filtered.Blocks {
+		n, err := dec.node(block)
+		if err != nil {
+			return fmt.Errorf("invalid filtered document: %w", err)
+		}
+		root.AppendChild(root, n)
+	}
+
+	// The original source stays a prefix of the new one, so opaque nodes
+	// keep pointing at valid text
+	doc.source = dec.source
+	doc.root = root
+	doc.meta = nil
+	for key, value := range filtered.Meta {
+		if doc.meta == nil {
+			doc.meta = make(map[string]interface{}, len(filtered.Meta))
+		}
+		doc.meta[key] = fromJSONValue(value)
+	}
+	return nil
+}
+
+// runFilter runs one filter command. The command is split on spaces, so
+// "python3 filters/links.py" works as well as a plain executable.
+func runFilter(ctx context.Context, command string, format string, input []byte) ([]byte, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty filter command")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], format)...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait on output pipes held open by orphaned children after a kill
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("filter %q: %w", command, ctx.Err())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("filter %q failed: %w: %s", command, err, msg)
+		}
+		return nil, fmt.Errorf("filter %q failed: %w", command, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// filterEncoder converts a goldmark tree to filter nodes
+type filterEncoder struct {
+	source []byte
+	opaque map[int]ast.Node
+}
+
+func (e *filterEncoder) document(doc *document, format string) *filterDocument {
+	out := &filterDocument{
+		Version: FilterASTVersion,
+		Format:  format,
+		Meta:    make(map[string]interface{}),
+		Blocks:  e.children(doc.root),
+	}
+	if doc.meta != nil {
+		out.Meta = toJSONValue(doc.meta).(map[string]interface{})
+	}
+	return out
+}
+
+func (e *filterEncoder) children(parent ast.Node) []*filterNode {
+	var nodes []*filterNode
+	for c := parent.FirstChild(); c != nil; c = c.NextSibling() {
+		nodes = append(nodes, e.node(c))
+	}
+	return nodes
+}
+
+// lines joins the source lines of a block
+func (e *filterEncoder) lines(n ast.Node) string {
+	var sb strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		sb.Write(line.Value(e.source))
+	}
+	return sb.String()
+}
+
+func (e *filterEncoder) node(n ast.Node) *filterNode {
+	out := &filterNode{Attributes: attributeStrings(n)}
+	withChildren := true
+
+	switch n := n.(type) {
+	case *ast.Paragraph:
+		out.Type = filterParagraph
+	case *ast.TextBlock:
+		out.Type = filterTextBlock
+	case *ast.Heading:
+		out.Type = filterHeading
+		out.Level = n.Level
+	case *ast.ThematicBreak:
+		out.Type = filterRule
+	case *ast.FencedCodeBlock:
+		out.Type = filterCodeBlock
+		out.Fenced = true
+		if n.Info != nil {
+			out.Info = string(n.Info.Segment.Value(e.source))
+		}
+		out.Text = e.lines(n)
+	case *ast.CodeBlock:
+		out.Type = filterCodeBlock
+		out.Text = e.lines(n)
+	case *ast.Blockquote:
+		out.Type = filterBlockquote
+	case *ast.List:
+		out.Type = filterList
+		out.Marker = string(n.Marker)
+		out.Start = n.Start
+		out.Tight = n.IsTight
+	case *ast.ListItem:
+		out.Type = filterListItem
+	case *ast.HTMLBlock:
+		out.Type = filterHTMLBlock
+		out.Text = e.lines(n)
+		if n.HasClosure() {
+			out.Text += string(n.ClosureLine.Value(e.source))
+		}
+	case *ast.Text:
+		out.Type = filterText
+		out.Text = string(n.Segment.Value(e.source))
+		out.SoftBreak = n.SoftLineBreak()
+		out.HardBreak = n.HardLineBreak()
+		out.Raw = n.IsRaw()
+	case *ast.String:
+		out.Type = filterString
+		out.Text = string(n.Value)
+		out.Raw = n.IsRaw()
+		out.HTML = n.IsCode()
+	case *ast.CodeSpan:
+		out.Type = filterCode
+		out.Text = string(n.Text(e.source))
+		withChildren = false
+	case *ast.Emphasis:
+		out.Type = filterEmphasis
+		out.Level = n.Level
+	case *ast.Link:
+		out.Type = filterLink
+		out.Destination = string(n.Destination)
+		out.Title = string(n.Title)
+	case *ast.Image:
+		out.Type = filterImage
+		out.Destination = string(n.Destination)
+		out.Title = string(n.Title)
+	case *ast.AutoLink:
+		out.Type = filterAutoLink
+		out.Destination = string(n.URL(e.source))
+		out.Text = string(n.Label(e.source))
+		out.Email = n.AutoLinkType == ast.AutoLinkEmail
+	case *ast.RawHTML:
+		out.Type = filterRawHTML
+		for i := 0; i < n.Segments.Len(); i++ {
+			segment := n.Segments.At(i)
+			out.Text += string(segment.Value(e.source))
+		}
+	case *east.Table:
+		out.Type = filterTable
+		for _, alignment := range n.Alignments {
+			out.Alignments = append(out.Alignments, alignment.String())
+		}
+	case *east.TableHeader:
+		out.Type = filterTableHeader
+	case *east.TableRow:
+		out.Type = filterTableRow
+	case *east.TableCell:
+		out.Type = filterTableCell
+		out.Alignment = n.Alignment.String()
+	case *east.Strikethrough:
+		out.Type = filterStrike
+	case *east.TaskCheckBox:
+		out.Type = filterCheckBox
+		out.Checked = n.IsChecked
+	default:
+		out.Type = n.Kind().String()
+		out.Opaque = len(e.opaque) + 1
+		e.opaque[out.Opaque] = n
+	}
+
+	if withChildren {
+		out.Children = e.children(n)
+	}
+	return out
+}
+
+// attributeStrings returns the attributes of n as strings
+func attributeStrings(n ast.Node) map[string]string {
+	if n.Attributes() == nil {
+		return nil
+	}
+	attrs := make(map[string]string)
+	for _, attr := range n.Attributes() {
+		switch v := attr.Value.(type) {
+		case []byte:
+			attrs[string(attr.Name)] = string(v)
+		default:
+			attrs[string(attr.Name)] = fmt.Sprint(v)
+		}
+	}
+	return attrs
+}
+
+// filterDecoder rebuilds a goldmark tree from filter nodes. New text is
+// appended to source, after the original markdown.
+type filterDecoder struct {
+	source []byte
+	opaque map[int]ast.Node
+	used   map[int]bool
+}
+
+// segment appends s to the source and returns its position
+func (d *filterDecoder) segment(s string) text.Segment {
+	start := len(d.source)
+	d.source = append(d.source, s...)
+	return text.NewSegment(start, len(d.source))
+}
+
+// setLines stores s as the source lines of a block
+func (d *filterDecoder) setLines(n ast.Node, s string) {
+	lines := text.NewSegments()
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" {
+			lines.Append(d.segment(line))
+		}
+	}
+	n.SetLines(lines)
+}
+
+func (d *filterDecoder) node(in *filterNode) (ast.Node, error) {
+	if in == nil {
+		return nil, fmt.Errorf("null node")
+	}
+
+	var n ast.Node
+	withChildren := true
+
+	switch {
+	case in.Opaque != 0:
+		original, ok := d.opaque[in.Opaque]
+		if !ok {
+			return nil, fmt.Errorf("unknown opaque node %d", in.Opaque)
+		}
+		if d.used[in.Opaque] {
+			return nil, fmt.Errorf("opaque node %d used more than once", in.Opaque)
+		}
+		d.used[in.Opaque] = true
+		n = original
+
+	case in.Type == filterParagraph:
+		n = ast.NewParagraph()
+	case in.Type == filterTextBlock:
+		n = ast.NewTextBlock()
+	case in.Type == filterHeading:
+		if in.Level < 1 || in.Level > 6 {
+			return nil, fmt.Errorf("invalid heading level %d", in.Level)
+		}
+		n = ast.NewHeading(in.Level)
+	case in.Type == filterRule:
+		n = ast.NewThematicBreak()
+	case in.Type == filterCodeBlock:
+		if in.Fenced || in.Info != "" {
+			var info *ast.Text
+			if in.Info != "" {
+				info = ast.NewTextSegment(d.segment(in.Info))
+			}
+			n = ast.NewFencedCodeBlock(info)
+		} else {
+			n = ast.NewCodeBlock()
+		}
+		d.setLines(n, in.Text)
+		withChildren = false
+	case in.Type == filterBlockquote:
+		n = ast.NewBlockquote()
+	case in.Type == filterList:
+		marker := byte('-')
+		if in.Marker != "" {
+			marker = in.Marker[0]
+		}
+		list := ast.NewList(marker)
+		list.IsTight = in.Tight
+		if list.IsOrdered() {
+			list.Start = in.Start
+			if list.Start == 0 {
+				list.Start = 1
+			}
+		}
+		n = list
+	case in.Type == filterListItem:
+		n = ast.NewListItem(0)
+	case in.Type == filterHTMLBlock:
+		n = ast.NewHTMLBlock(ast.HTMLBlockType7)
+		d.setLines(n, in.Text)
+		withChildren = false
+	case in.Type == filterText:
+		t := ast.NewTextSegment(d.segment(in.Text))
+		t.SetSoftLineBreak(in.SoftBreak)
+		t.SetHardLineBreak(in.HardBreak)
+		t.SetRaw(in.Raw)
+		n = t
+		withChildren = false
+	case in.Type == filterString:
+		s := ast.NewString([]byte(in.Text))
+		s.SetRaw(in.Raw)
+		s.SetCode(in.HTML)
+		n = s
+		withChildren = false
+	case in.Type == filterCode:
+		code := ast.NewCodeSpan()
+		code.AppendChild(code, ast.NewRawTextSegment(d.segment(in.Text)))
+		n = code
+		withChildren = false
+	case in.Type == filterEmphasis:
+		level := in.Level
+		if level != 2 {
+			level = 1
+		}
+		n = ast.NewEmphasis(level)
+	case in.Type == filterLink:
+		link := ast.NewLink()
+		link.Destination = []byte(in.Destination)
+		if in.Title != "" {
+			link.Title = []byte(in.Title)
+		}
+		n = link
+	case in.Type == filterImage:
+		link := ast.NewLink()
+		link.Destination = []byte(in.Destination)
+		if in.Title != "" {
+			link.Title = []byte(in.Title)
+		}
+		n = ast.NewImage(link)
+	case in.Type == filterAutoLink:
+		// An autolink whose target was rewritten becomes a plain link
+		if in.Destination == "" || in.Destination == in.Text {
+			typ := ast.AutoLinkURL
+			if in.Email {
+				typ = ast.AutoLinkEmail
+			}
+			n = ast.NewAutoLink(typ, ast.NewTextSegment(d.segment(in.Text)))
+		} else {
+			link := ast.NewLink()
+			link.Destination = []byte(in.Destination)
+			link.AppendChild(link, ast.NewTextSegment(d.segment(in.Text)))
+			n = link
+		}
+		withChildren = false
+	case in.Type == filterRawHTML:
+		raw := ast.NewRawHTML()
+		raw.Segments.Append(d.segment(in.Text))
+		n = raw
+		withChildren = false
+	case in.Type == filterTable:
+		table := east.NewTable()
+		for _, alignment := range in.Alignments {
+			table.Alignments = append(table.Alignments, parseAlignment(alignment))
+		}
+		n = table
+	case in.Type == filterTableHeader, in.Type == filterTableRow:
+		row := east.NewTableRow(nil)
+		for _, child := range in.Children {
+			cell, err := d.node(child)
+			if err != nil {
+				return nil, err
+			}
+			row.AppendChild(row, cell)
+		}
+		if in.Type == filterTableHeader {
+			n = east.NewTableHeader(row)
+		} else {
+			n = row
+		}
+		withChildren = false
+	case in.Type == filterTableCell:
+		cell := east.NewTableCell()
+		cell.Alignment = parseAlignment(in.Alignment)
+		n = cell
+	case in.Type == filterStrike:
+		n = east.NewStrikethrough()
+	case in.Type == filterCheckBox:
+		n = east.NewTaskCheckBox(in.Checked)
+		withChildren = false
+
+	default:
+		return nil, fmt.Errorf("unsupported node type %q", in.Type)
+	}
+
+	if withChildren {
+		var children []ast.Node
+		for _, child := range in.Children {
+			c, err := d.node(child)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, c)
+		}
+		n.RemoveChildren(n)
+		for _, c := range children {
+			n.AppendChild(n, c)
+		}
+	}
+
+	if in.Opaque == 0 {
+		setAttributes(n, in.Attributes)
+	}
+	return n, nil
+}
+
+// setAttributes sets attrs on n with the id first, as the parser does, and
+// the rest sorted so the output is stable
+func setAttributes(n ast.Node, attrs map[string]string) {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		if name != "id" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := attrs["id"]; ok {
+		names = append([]string{"id"}, names...)
+	}
+	for _, name := range names {
+		n.SetAttributeString(name, []byte(attrs[name]))
+	}
+}
+
+func parseAlignment(s string) east.Alignment {
+	switch s {
+	case "left":
+		return east.AlignLeft
+	case "right":
+		return east.AlignRight
+	case "center":
+		return east.AlignCenter
+	}
+	return east.AlignNone
+}
+
+// toJSONValue converts front matter into values encoding/json accepts; YAML
+// decodes nested mappings with interface{} keys
+func toJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = toJSONValue(item)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[fmt.Sprint(key)] = toJSONValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = toJSONValue(item)
+		}
+		return out
+	}
+	return value
+}
+
+// fromJSONValue turns nested objects back into interface{} keyed maps and
+// whole JSON numbers back into ints, matching what YAML front matter
+// produces
+func fromJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[interface{}]interface{}, len(v))
+		for key, item := range v {
+			out[key] = fromJSONValue(item)
+		}
+		return out
+	case []interface{}:
+		for i, item := range v {
+			v[i] = fromJSONValue(item)
+		}
+		return v
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int(v)
+		}
+	}
+	return value
+}
@@ -0,0 +1,279 @@
+package converter
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FontConfig selects the typefaces used for each text role. Families are
+// matched against font files in Dir by file name, e.g. "Noto Sans" matches
+// NotoSans-Regular.ttf and NotoSans-BoldItalic.ttf. Families without files
+// in Dir are still referenced by name, so installed system fonts work too.
+type FontConfig struct {
+	Dir      string // Directory containing .ttf, .otf, .woff or .woff2 files
+	Body     string // Family for body text
+	Headings string // Family for headings; defaults to Body
+	Code     string // Family for inline code and code blocks
+
+	// Embed inlines font files as data URIs so HTML output is
+	// self-contained. Otherwise @font-face rules link to the local files.
+	Embed bool
+}
+
+// FontFace is a single font file belonging to a family
+type FontFace struct {
+	Family string
+	Path   string
+	Weight int
+	Italic bool
+	Format string // CSS format hint: truetype, opentype, woff or woff2
+}
+
+var fontFormats = map[string]string{
+	".ttf":   "truetype",
+	".otf":   "opentype",
+	".woff":  "woff",
+	".woff2": "woff2",
+}
+
+// fontWeights maps file name style suffixes to CSS weights, longest first
+var fontWeights = []struct {
+	name   string
+	weight int
+}{
+	{"extralight", 200},
+	{"ultralight", 200},
+	{"extrabold", 800},
+	{"ultrabold", 800},
+	{"semibold", 600},
+	{"demibold", 600},
+	{"regular", 400},
+	{"medium", 500},
+	{"light", 300},
+	{"black", 900},
+	{"heavy", 900},
+	{"thin", 100},
+	{"bold", 700},
+	{"book", 400},
+}
+
+// headingsFamily returns the headings family, falling back to the body family
+func (c *FontConfig) headingsFamily() string {
+	if c.Headings != "" {
+		return c.Headings
+	}
+	return c.Body
+}
+
+// families returns the distinct configured families
+func (c *FontConfig) families() []string {
+	seen := map[string]bool{}
+	var families []string
+	for _, family := range []string{c.Body, c.headingsFamily(), c.Code} {
+		if family != "" && !seen[family] {
+			seen[family] = true
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+// Faces returns the font files in Dir that belong to the configured families
+func (c *FontConfig) Faces() ([]FontFace, error) {
+	if c.Dir == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font directory %s: %w", c.Dir, err)
+	}
+
+	var faces []FontFace
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		format, ok := fontFormats[ext]
+		if entry.IsDir() || !ok {
+			continue
+		}
+
+		stem := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		for _, family := range c.families() {
+			style, ok := matchFontFile(stem, family)
+			if !ok {
+				continue
+			}
+			path, err := filepath.Abs(filepath.Join(c.Dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			weight, italic := parseFontStyle(style)
+			faces = append(faces, FontFace{
+				Family: family,
+				Path:   path,
+				Weight: weight,
+				Italic: italic,
+				Format: format,
+			})
+		}
+	}
+
+	sort.Slice(faces, func(i, j int) bool {
+		if faces[i].Family != faces[j].Family {
+			return faces[i].Family < faces[j].Family
+		}
+		if faces[i].Weight != faces[j].Weight {
+			return faces[i].Weight < faces[j].Weight
+		}
+		return !faces[i].Italic && faces[j].Italic
+	})
+
+	return faces, nil
+}
+
+// matchFontFile reports whether a file name stem belongs to family and
+// returns the remaining style part, e.g. "Bold" for NotoSans-Bold
+func matchFontFile(stem string, family string) (string, bool) {
+	key := fontKey(family)
+	name := fontKey(stem)
+	if !strings.HasPrefix(name, key) {
+		return "", false
+	}
+
+	style := name[len(key):]
+	if style != "" && !isFontStyle(style) {
+		// e.g. "NotoSansMono" must not match the "Noto Sans" family
+		return "", false
+	}
+	return style, true
+}
+
+// fontKey lower-cases a family or file name and drops separators
+func fontKey(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r != ' ' && r != '-' && r != '_' {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func isFontStyle(style string) bool {
+	style = strings.TrimSuffix(strings.TrimSuffix(style, "italic"), "oblique")
+	if style == "" || style == "it" {
+		return true
+	}
+	for _, w := range fontWeights {
+		if style == w.name {
+			return true
+		}
+	}
+	return false
+}
+
+func parseFontStyle(style string) (int, bool) {
+	italic := strings.HasSuffix(style, "italic") || strings.HasSuffix(style, "oblique") || style == "it"
+	style = strings.TrimSuffix(strings.TrimSuffix(style, "italic"), "oblique")
+	for _, w := range fontWeights {
+		if style == w.name {
+			return w.weight, italic
+		}
+	}
+	return 400, italic
+}
+
+// fontCSS returns @font-face rules and role assignments for the config
+func fontCSS(c *FontConfig) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+
+	faces, err := c.Faces()
+	if err != nil {
+		return "", err
+	}
+
+	var css strings.Builder
+	for _, face := range faces {
+		src, err := face.source(c.Embed)
+		if err != nil {
+			return "", err
+		}
+		style := "normal"
+		if face.Italic {
+			style = "italic"
+		}
+		fmt.Fprintf(&css, `
+		@font-face {
+			font-family: %q;
+			src: url("%s") format(%q);
+			font-weight: %d;
+			font-style: %s;
+		}`, face.Family, src, face.Format, face.Weight, style)
+	}
+
+	if c.Body != "" {
+		fmt.Fprintf(&css, `
+		body,
+		.markdown-content {
+			font-family: %q, sans-serif;
+		}`, c.Body)
+	}
+	if family := c.headingsFamily(); family != "" {
+		fmt.Fprintf(&css, `
+		.markdown-content h1,
+		.markdown-content h2,
+		.markdown-content h3,
+		.markdown-content h4,
+		.markdown-content h5,
+		.markdown-content h6 {
+			font-family: %q, sans-serif;
+		}`, family)
+	}
+	if c.Code != "" {
+		fmt.Fprintf(&css, `
+		.markdown-content code,
+		.markdown-content pre,
+		code[class*="language-"],
+		pre[class*="language-"] {
+			font-family: %q, monospace !important;
+		}`, c.Code)
+	}
+
+	return css.String(), nil
+}
+
+// source returns the URL for a face: a data URI when embedding, otherwise
+// a file URL that wkhtmltopdf can load with local file access enabled
+func (f FontFace) source(embed bool) (string, error) {
+	if embed {
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read font %s: %w", f.Path, err)
+		}
+		return "data:font/" + fontMIME(f.Format) + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+	}
+
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(f.Path)}
+	if !strings.HasPrefix(u.Path, "/") {
+		// Windows drive paths need a leading slash: file:///C:/...
+		u.Path = "/" + u.Path
+	}
+	return u.String(), nil
+}
+
+func fontMIME(format string) string {
+	switch format {
+	case "truetype":
+		return "ttf"
+	case "opentype":
+		return "otf"
+	}
+	return format
+}
@@ -0,0 +1,223 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Image formats accepted by ImageOptions
+const (
+	ImagePNG  = "png"
+	ImageJPEG = "jpg"
+)
+
+// DefaultImageWidth is the viewport width used when none is configured
+const DefaultImageWidth = 1024
+
+// ImageOptions configures image output
+type ImageOptions struct {
+	Format  string // png or jpg; defaults to png
+	Width   int    // Viewport width in pixels; defaults to DefaultImageWidth
+	Quality int    // JPEG quality from 1 to 100; 0 keeps the wkhtmltoimage default
+
+	// Crop cuts a rectangle out of the rendered page; a zero width or
+	// height keeps the full extent
+	CropX, CropY, CropWidth, CropHeight int
+
+	// FirstPage limits the image to the height of one A4 page at Width,
+	// which suits thumbnails and social previews
+	FirstPage bool
+}
+
+// withDefaults fills unset fields
+func (o ImageOptions) withDefaults() ImageOptions {
+	switch strings.ToLower(o.Format) {
+	case "jpg", "jpeg":
+		o.Format = ImageJPEG
+	default:
+		o.Format = ImagePNG
+	}
+	if o.Width <= 0 {
+		o.Width = DefaultImageWidth
+	}
+	if o.FirstPage && o.CropHeight <= 0 {
+		o.CropHeight = int(math.Round(float64(o.Width) * 297 / 210))
+	}
+	return o
+}
+
+// Extension returns the file extension of the configured format
+func (o ImageOptions) Extension() string {
+	return o.withDefaults().Format
+}
+
+// ContentType returns the MIME type of the configured format
+func (o ImageOptions) ContentType() string {
+	if o.withDefaults().Format == ImageJPEG {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// args builds the wkhtmltoimage command line options
+func (o ImageOptions) args() []string {
+	args := []string{
+		"--format", o.Format,
+		"--width", strconv.Itoa(o.Width),
+		"--enable-local-file-access",
+		"--load-error-handling", "ignore",
+		"--load-media-error-handling", "ignore",
+		"--javascript-delay", "1000",
+		"--quiet",
+	}
+	if o.Quality > 0 {
+		args = append(args, "--quality", strconv.Itoa(o.Quality))
+	}
+	if o.CropWidth > 0 || o.CropHeight > 0 {
+		width := o.CropWidth
+		if width <= 0 {
+			width = o.Width - o.CropX
+		}
+		args = append(args,
+			"--crop-x", strconv.Itoa(o.CropX),
+			"--crop-y", strconv.Itoa(o.CropY),
+			"--crop-w", strconv.Itoa(width),
+		)
+		if o.CropHeight > 0 {
+			args = append(args, "--crop-h", strconv.Itoa(o.CropHeight))
+		}
+	}
+	return args
+}
+
+// FindWkhtmltoimage returns the path of the wkhtmltoimage executable. It is
+// found the same way as wkhtmltopdf and lives next to it when a wkhtmltopdf
+// path is configured.
+func FindWkhtmltoimage() (string, error) {
+	path, _, err := findExecutable("wkhtmltoimage")
+	return path, err
+}
+
+// IsWkhtmltoimageInstalled checks if wkhtmltoimage is installed
+func IsWkhtmltoimageInstalled() bool {
+	_, err := FindWkhtmltoimage()
+	return err == nil
+}
+
+// DetectWkhtmltoimage locates wkhtmltoimage and reports its version
+func DetectWkhtmltoimage() (*ExecutableInfo, error) {
+	return detectExecutable("wkhtmltoimage")
+}
+
+// RenderImage renders the HTML output for markdown to an image and writes it
+// to w. Nothing is written to w unless rendering succeeds.
+func RenderImage(w io.Writer, markdown string, opts Options, img ImageOptions) error {
+	return RenderImageContext(context.Background(), w, markdown, opts, img)
+}
+
+// RenderImageContext is RenderImage with cancellation and the per-document
+// timeout from opts; wkhtmltoimage is killed when ctx is done
+func RenderImageContext(ctx context.Context, w io.Writer, markdown string, opts Options, img ImageOptions) error {
+	img = img.withDefaults()
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	// Create a private workspace for this job
+	workDir, err := os.MkdirTemp("", "md2img-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	tempHTML := filepath.Join(workDir, "document.html")
+	err = writeFile(tempHTML, func(f io.Writer) error {
+		return writeImageHTML(ctx, f, markdown, linkedFonts(opts))
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return contextError(ctx, opts.Timeout)
+		}
+		return err
+	}
+
+	bin, err := FindWkhtmltoimage()
+	if err != nil {
+		return err
+	}
+
+	// wkhtmltoimage writes the image to stdout when the output is "-"
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, append(img.args(), tempHTML, "-")...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait on output pipes held open by orphaned children after a kill
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return contextError(ctx, opts.Timeout)
+		}
+		return fmt.Errorf("failed to generate image: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return fmt.Errorf("failed to generate image: wkhtmltoimage produced no output")
+	}
+
+	if _, err := stdout.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write image output: %w", err)
+	}
+
+	return nil
+}
+
+// writeImageHTML writes the page wkhtmltoimage renders for markdown. Its
+// WebKit is the one inside wkhtmltopdf, so the page is prepared for print
+// the same way.
+func writeImageHTML(ctx context.Context, w io.Writer, markdown string, opts Options) error {
+	doc, err := parseDocument(markdown, opts)
+	if err != nil {
+		return err
+	}
+	if err := applyFilters(ctx, doc, opts, FilterFormatHTML); err != nil {
+		return err
+	}
+	if err := renderDiagrams(ctx, doc, opts); err != nil {
+		return err
+	}
+	if err := writePrintHTML(w, doc, opts); err != nil {
+		return fmt.Errorf("failed to convert markdown to HTML: %w", err)
+	}
+	return nil
+}
+
+// ConvertToImage converts markdown content to an image file
+func ConvertToImage(markdown string, outputPath string, opts Options, img ImageOptions) error {
+	return ConvertToImageContext(context.Background(), markdown, outputPath, opts, img)
+}
+
+// ConvertToImageContext is ConvertToImage with cancellation and the
+// per-document timeout from opts
+func ConvertToImageContext(ctx context.Context, markdown string, outputPath string, opts Options, img ImageOptions) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderImageContext(ctx, &buf, markdown, opts, img); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write image file: %w", err)
+	}
+
+	return nil
+}
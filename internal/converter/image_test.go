@@ -0,0 +1,35 @@
+package converter
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWriteImageHTMLUsesPrintCSS(t *testing.T) {
+	markdown := "Euler: $e^{i\\pi} + 1 = 0$\n\n```go copy\nfmt.Println()\n```\n"
+	opts := Options{Extensions: []string{ExtMath}}
+
+	var sb strings.Builder
+	if err := writeImageHTML(context.Background(), &sb, markdown, opts); err != nil {
+		t.Fatal(err)
+	}
+	page := sb.String()
+	if !strings.Contains(page, "<math") {
+		t.Fatal("image page has no MathML")
+	}
+	if !strings.Contains(page, mathPrintCSS) {
+		t.Error("image page lacks the MathML print CSS")
+	}
+	if !strings.Contains(page, codePrintCSS) {
+		t.Error("image page shows copy buttons")
+	}
+
+	screen, err := ConvertToHTMLWithOptions(markdown, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(screen, mathPrintCSS) {
+		t.Error("screen page has the MathML print CSS")
+	}
+}
@@ -0,0 +1,313 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Includes enables {{< include "path" >}} directives, which splice other
+// markdown files into a document before it is parsed. A directive stands
+// on a line of its own and may shift the headings of the included file,
+// e.g. {{< include "shared/install.md" shift=1 >}}. Included files may
+// include others; their front matter is dropped and their relative image
+// paths are rebased onto the document's directory.
+//
+// Fenced code blocks with a file argument, such as ```go file=main.go
+// lines=20-40 or ```file=setup.py region=install, are filled from that
+// file when the document is converted, and the language defaults to the
+// file's.
+type Includes struct {
+	// Root is the directory included files must stay inside. Paths
+	// starting with / are relative to it.
+	Root string
+
+	// Dir is the directory of the document, which its include paths are
+	// relative to; empty uses Root
+	Dir string
+}
+
+// includePattern matches an include directive line
+var includePattern = regexp.MustCompile(`^ {0,3}\{\{<\s*include\s+"([^"]+)"(?:\s+shift=(-?[0-9]+))?\s*>\}\}[ \t]*$`)
+
+// fencePattern matches the opening or closing line of a fenced code block
+var fencePattern = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+
+// atxHeadingPattern matches the marker of an ATX heading
+var atxHeadingPattern = regexp.MustCompile(`^( {0,3})(#{1,6})([ \t\r\n]|$)`)
+
+// Relative paths of markdown images and HTML img tags
+var (
+	imagePathPattern = regexp.MustCompile(`(!\[(?:[^\[\]\\]|\\.)*\]\([ \t]*<?)([^\s<>)]+)`)
+	imageSrcPattern  = regexp.MustCompile(`(<img\b[^>]*?\bsrc[ \t]*=[ \t]*["'])([^"']+)`)
+	uriSchemePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:`)
+)
+
+// expandIncludes replaces the include directives of source, outside code
+// blocks, with the files they name, and fills code blocks that reference
+// a file. It returns source unchanged when inc is
+// nil.
+func expandIncludes(source []byte, inc *Includes) ([]byte, error) {
+	expanded, _, err := expandIncludesLines(source, inc)
+	return expanded, err
+}
+
+// expandIncludesLines is expandIncludes that also maps the lines of the
+// expanded source back to source
+func expandIncludesLines(source []byte, inc *Includes) ([]byte, lineMap, error) {
+	if inc == nil || !bytes.Contains(source, []byte("{{<")) && !bytes.Contains(source, []byte(codeFileArg+"=")) {
+		return source, nil, nil
+	}
+
+	x, err := inc.includer()
+	if err != nil {
+		return nil, nil, err
+	}
+	x.lines = lineMap{}
+	var sb strings.Builder
+	if err := x.expand(&sb, string(source), x.top, 0, nil); err != nil {
+		return nil, nil, err
+	}
+	return []byte(sb.String()), x.lines, nil
+}
+
+// lineMap maps the lines of an expanded document back to its source:
+// entry i is the expanded line, counted from 0, where source line i+1
+// starts. A nil lineMap maps every line to itself.
+type lineMap []int
+
+// line returns the source line of the expanded line n, both counted from 1
+func (m lineMap) line(n int) int {
+	if m == nil {
+		return n
+	}
+	return sort.Search(len(m), func(i int) bool { return m[i] > n-1 })
+}
+
+// includer returns an includer for a document in inc.Dir
+func (inc *Includes) includer() (*includer, error) {
+	root, err := realPath(inc.Root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include root: %w", err)
+	}
+	dir := root
+	if inc.Dir != "" {
+		if dir, err = realPath(inc.Dir); err != nil {
+			return nil, fmt.Errorf("invalid document directory: %w", err)
+		}
+	}
+	return &includer{root: root, top: dir}, nil
+}
+
+// file returns the real path of a file the document names, relative to its
+// directory or, with a leading slash, to the root it must stay inside
+func (inc *Includes) file(path string) (string, error) {
+	x, err := inc.includer()
+	if err != nil {
+		return "", err
+	}
+	return x.resolve(path, x.top)
+}
+
+// realPath returns the absolute path of path with symbolic links resolved
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// includer splices included files into a document
+type includer struct {
+	root string // Sandbox directory
+	top  string // Directory of the document, for rebasing image paths
+
+	lines    lineMap // Where the document's lines start, when not nil
+	newlines int     // Newlines written up to counted
+	counted  int     // Bytes of output whose newlines are counted
+}
+
+// mark records that the next line of the document starts at the end of sb
+func (x *includer) mark(sb *strings.Builder) {
+	out := sb.String()
+	x.newlines += strings.Count(out[x.counted:], "\n")
+	x.counted = len(out)
+	x.lines = append(x.lines, x.newlines)
+}
+
+// expand writes text, a file in dir, to sb with its directives expanded
+// and its headings shifted by shift levels. stack holds the files being
+// included, outermost first.
+func (x *includer) expand(sb *strings.Builder, text, dir string, shift int, stack []string) error {
+	var (
+		fence   string // Marker of the open code fence, if any
+		replace bool   // The open fence's content comes from a file
+	)
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if x.lines != nil && len(stack) == 0 {
+			x.mark(sb)
+		}
+		content := strings.TrimRight(line, "\r\n")
+		if fence != "" {
+			if m := fencePattern.FindStringSubmatch(content); m != nil && m[1][0] == fence[0] &&
+				len(m[1]) >= len(fence) && strings.TrimSpace(m[2]) == "" {
+				fence = ""
+			}
+			if !replace {
+				sb.WriteString(line)
+			}
+			continue
+		}
+		if m := fencePattern.FindStringSubmatch(content); m != nil && !(m[1][0] == '`' && strings.Contains(m[2], "`")) {
+			fence = m[1]
+			args, rest := codeFileArgs(m[2])
+			_, replace = args[codeFileArg]
+			if !replace {
+				sb.WriteString(line)
+				continue
+			}
+			if err := x.codeFile(sb, content, m[1], args, rest, dir); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if m := includePattern.FindStringSubmatch(content); m != nil {
+			levels, _ := strconv.Atoi(m[2])
+			if err := x.include(sb, m[1], dir, shift+levels, stack); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if shift != 0 {
+			line = shiftHeading(line, shift)
+		}
+		if dir != x.top {
+			line = x.rebaseImages(line, dir)
+		}
+		sb.WriteString(line)
+	}
+	return nil
+}
+
+// include expands the file at path, relative to dir or, with a leading
+// slash, to the root
+func (x *includer) include(sb *strings.Builder, path, dir string, shift int, stack []string) error {
+	real, err := x.resolve(path, dir)
+	if err != nil {
+		return err
+	}
+	for i, file := range stack {
+		if file == real {
+			return fmt.Errorf("include cycle: %s", x.chain(append(stack[i:], real)))
+		}
+	}
+
+	data, err := os.ReadFile(real)
+	if err != nil {
+		return fmt.Errorf("failed to include %s: %w", path, err)
+	}
+	_, body := splitFrontMatter(string(data))
+	if err := x.expand(sb, body, filepath.Dir(real), shift, append(stack, real)); err != nil {
+		return err
+	}
+	// The line after the directive must not join the last included line
+	if body != "" && !strings.HasSuffix(body, "\n") {
+		sb.WriteString("\n")
+	}
+	return nil
+}
+
+// resolve returns the real path of the file path names from dir. Paths
+// with a leading slash start from the root, and no path may leave it.
+func (x *includer) resolve(path, dir string) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(path))
+	if strings.HasPrefix(path, "/") {
+		target = filepath.Join(x.root, filepath.FromSlash(path))
+	}
+	// Missing files outside the root are reported as outside, so includes
+	// cannot probe the file system
+	real, err := filepath.EvalSymlinks(target)
+	if err != nil && within(x.root, target) {
+		return "", fmt.Errorf("failed to include %s: %w", path, err)
+	}
+	if err != nil || !within(x.root, real) {
+		return "", fmt.Errorf("include %s is outside %s", path, x.root)
+	}
+	return real, nil
+}
+
+// within reports whether path lies inside dir
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// chain formats the files of an include cycle relative to the root
+func (x *includer) chain(files []string) string {
+	names := make([]string, len(files))
+	for i, file := range files {
+		if rel, err := filepath.Rel(x.root, file); err == nil {
+			file = filepath.ToSlash(rel)
+		}
+		names[i] = file
+	}
+	return strings.Join(names, " -> ")
+}
+
+// shiftHeading moves an ATX heading line by shift levels, within 1 to 6
+func shiftHeading(line string, shift int) string {
+	m := atxHeadingPattern.FindStringSubmatchIndex(line)
+	if m == nil {
+		return line
+	}
+	level := m[5] - m[4] + shift
+	if level < 1 {
+		level = 1
+	} else if level > 6 {
+		level = 6
+	}
+	return line[:m[4]] + strings.Repeat("#", level) + line[m[5]:]
+}
+
+// rebaseImages rewrites relative image paths of a line from a file in dir
+// so they resolve from the document's directory
+func (x *includer) rebaseImages(line, dir string) string {
+	rebase := func(pattern *regexp.Regexp, line string) string {
+		return pattern.ReplaceAllStringFunc(line, func(match string) string {
+			m := pattern.FindStringSubmatch(match)
+			return m[1] + x.rebase(m[2], dir)
+		})
+	}
+	if strings.Contains(line, "![") {
+		line = rebase(imagePathPattern, line)
+	}
+	if strings.Contains(line, "<img") {
+		line = rebase(imageSrcPattern, line)
+	}
+	return line
+}
+
+// rebase returns dest, a path relative to dir, relative to the document's
+// directory. URLs, absolute paths and fragments are returned unchanged.
+func (x *includer) rebase(dest, dir string) string {
+	if uriSchemePattern.MatchString(dest) || strings.HasPrefix(dest, "/") || strings.HasPrefix(dest, "#") {
+		return dest
+	}
+	path, suffix := dest, ""
+	if i := strings.IndexAny(dest, "?#"); i >= 0 {
+		path, suffix = dest[:i], dest[i:]
+	}
+	rel, err := filepath.Rel(x.top, filepath.Join(dir, filepath.FromSlash(path)))
+	if err != nil {
+		return dest
+	}
+	return filepath.ToSlash(rel) + suffix
+}
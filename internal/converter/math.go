@@ -0,0 +1,256 @@
+package converter
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindMathInline is the node kind of $...$ and $$...$$ inside a paragraph
+var KindMathInline = ast.NewNodeKind("MathInline")
+
+// MathInline is TeX math inside a paragraph
+type MathInline struct {
+	ast.BaseInline
+
+	// Segment is the TeX source between the dollar signs
+	Segment text.Segment
+
+	// Display is set for $$...$$, which is set on a line of its own
+	Display bool
+}
+
+// Kind implements ast.Node
+func (n *MathInline) Kind() ast.NodeKind {
+	return KindMathInline
+}
+
+// Dump implements ast.Node
+func (n *MathInline) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"TeX": string(n.Segment.Value(source))}, nil)
+}
+
+// KindMathBlock is the node kind of a $$ block or a ```math fence
+var KindMathBlock = ast.NewNodeKind("MathBlock")
+
+// MathBlock is display math on lines of its own; its lines hold the TeX
+// source
+type MathBlock struct {
+	ast.BaseBlock
+
+	closed bool // The closing $$ has been read
+}
+
+// Kind implements ast.Node
+func (n *MathBlock) Kind() ast.NodeKind {
+	return KindMathBlock
+}
+
+// IsRaw implements ast.Node
+func (n *MathBlock) IsRaw() bool {
+	return true
+}
+
+// Dump implements ast.Node
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// mathTeX returns the TeX source of a math node
+func mathTeX(n ast.Node, source []byte) (tex string, display bool) {
+	switch n := n.(type) {
+	case *MathInline:
+		return string(n.Segment.Value(source)), n.Display
+	case *MathBlock:
+		return strings.TrimSpace(string(blockText(n, source))), true
+	}
+	return "", false
+}
+
+// mathInlineParser parses $...$ and $$...$$. Like pandoc, it needs a
+// non-space character right inside the dollars and no digit right after
+// the closing one, so prices such as $5 and $10 stay text.
+type mathInlineParser struct{}
+
+// Trigger implements parser.InlineParser
+func (p *mathInlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Parse implements parser.InlineParser
+func (p *mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+
+	if bytes.HasPrefix(line, []byte("$$")) {
+		end := bytes.Index(line[2:], []byte("$$"))
+		if end <= 0 || len(bytes.TrimSpace(line[2:2+end])) == 0 {
+			return nil
+		}
+		block.Advance(end + 4)
+		return &MathInline{Segment: text.NewSegment(segment.Start+2, segment.Start+2+end), Display: true}
+	}
+
+	if len(line) < 3 || util.IsSpace(line[1]) {
+		return nil
+	}
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '$':
+			if util.IsSpace(line[i-1]) || (i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9') {
+				continue
+			}
+			block.Advance(i + 1)
+			return &MathInline{Segment: text.NewSegment(segment.Start+1, segment.Start+i)}
+		}
+	}
+	return nil
+}
+
+// mathBlockParser parses display math between $$ lines, or on a single
+// $$...$$ line
+type mathBlockParser struct{}
+
+// Trigger implements parser.BlockParser
+func (p *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Open implements parser.BlockParser
+func (p *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+	start := segment.Start + pos + 2
+	rest := util.TrimRightSpace(line[pos+2:])
+
+	node := &MathBlock{}
+	if end := bytes.Index(rest, []byte("$$")); end >= 0 {
+		// Text after the closing dollars makes this inline math
+		if end+2 != len(rest) {
+			return nil, parser.NoChildren
+		}
+		node.Lines().Append(text.NewSegment(start, start+end))
+		node.closed = true
+		reader.Advance(segment.Len() - 1)
+		return node, parser.NoChildren
+	}
+
+	if len(util.TrimLeftSpace(rest)) > 0 {
+		node.Lines().Append(text.NewSegment(start, segment.Stop))
+	}
+	reader.Advance(segment.Len() - 1)
+	return node, parser.NoChildren
+}
+
+// Continue implements parser.BlockParser
+func (p *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if line == nil || node.(*MathBlock).closed {
+		return parser.Close
+	}
+	trimmed := util.TrimRightSpace(line)
+	if bytes.HasSuffix(trimmed, []byte("$$")) {
+		node.Lines().Append(text.NewSegment(segment.Start, segment.Start+len(trimmed)-2))
+		reader.Advance(segment.Len() - 1)
+		return parser.Close
+	}
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return parser.Continue | parser.NoChildren
+}
+
+// Close implements parser.BlockParser
+func (p *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+// CanInterruptParagraph implements parser.BlockParser
+func (p *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+// CanAcceptIndentedLine implements parser.BlockParser
+func (p *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// mathFences turns ```math code blocks into math blocks
+type mathFences struct{}
+
+// Transform implements parser.ASTTransformer
+func (t *mathFences) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var fences []*ast.FencedCodeBlock
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if fence, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			if string(fence.Language(source)) == "math" {
+				fences = append(fences, fence)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, fence := range fences {
+		block := &MathBlock{}
+		block.SetLines(fence.Lines())
+		fence.Parent().ReplaceChild(fence.Parent(), fence, block)
+	}
+}
+
+// mathHTMLRenderer renders math nodes to MathML
+type mathHTMLRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *mathHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMathInline, r.render)
+	reg.Register(KindMathBlock, r.render)
+}
+
+func (r *mathHTMLRenderer) render(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	tex, display := mathTeX(n, source)
+	_, _ = w.WriteString(texToMathML(tex, display))
+	if n.Type() == ast.TypeBlock {
+		_ = w.WriteByte('\n')
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// mathExtension adds TeX math rendered to MathML
+type mathExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&mathBlockParser{}, 150)),
+		parser.WithInlineParsers(util.Prioritized(&mathInlineParser{}, 150)),
+		parser.WithASTTransformers(util.Prioritized(&mathFences{}, 100)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&mathHTMLRenderer{}, 500),
+	))
+}
+
+// hasMath reports whether the document contains math nodes
+func hasMath(root ast.Node) bool {
+	found := false
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if n.Kind() == KindMathInline || n.Kind() == KindMathBlock {
+			found = true
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return found
+}
@@ -0,0 +1,983 @@
+package converter
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// mathNode is a MathML element produced from TeX
+type mathNode struct {
+	tag      string
+	attrs    [][2]string
+	text     string // Content of token elements such as mi, mn and mo
+	children []*mathNode
+}
+
+func mathToken(tag, text string, attrs ...[2]string) *mathNode {
+	return &mathNode{tag: tag, text: text, attrs: attrs}
+}
+
+func mathElement(tag string, children ...*mathNode) *mathNode {
+	return &mathNode{tag: tag, children: children}
+}
+
+// mathRow wraps nodes in an mrow unless there is exactly one
+func mathRow(nodes []*mathNode) *mathNode {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return mathElement("mrow", nodes...)
+}
+
+func (n *mathNode) attr(name, value string) *mathNode {
+	n.attrs = append(n.attrs, [2]string{name, value})
+	return n
+}
+
+func (n *mathNode) isToken() bool {
+	switch n.tag {
+	case "mi", "mn", "mo", "mtext":
+		return true
+	}
+	return false
+}
+
+// Symbols typeset as identifiers
+var mathIdentifiers = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ",
+	"iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"omicron": "ο", "pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ",
+	"sigma": "σ", "varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ",
+	"varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"infty": "∞", "partial": "∂", "nabla": "∇", "emptyset": "∅",
+	"varnothing": "∅", "ell": "ℓ", "hbar": "ℏ", "aleph": "ℵ", "Re": "ℜ",
+	"Im": "ℑ", "wp": "℘", "imath": "ı", "jmath": "ȷ",
+}
+
+// Upright capital Greek letters
+var mathUprightIdentifiers = map[string]string{
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ",
+	"Omega": "Ω",
+}
+
+// Symbols typeset as operators, relations, arrows and delimiters
+var mathOperators = map[string]string{
+	"pm": "±", "mp": "∓", "times": "×", "div": "÷", "cdot": "⋅", "ast": "∗",
+	"star": "⋆", "circ": "∘", "bullet": "∙", "oplus": "⊕", "ominus": "⊖",
+	"otimes": "⊗", "oslash": "⊘", "odot": "⊙", "cap": "∩", "cup": "∪",
+	"sqcap": "⊓", "sqcup": "⊔", "vee": "∨", "lor": "∨", "wedge": "∧",
+	"land": "∧", "setminus": "∖", "wr": "≀", "leq": "≤", "le": "≤",
+	"geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠", "equiv": "≡",
+	"approx": "≈", "cong": "≅", "sim": "∼", "simeq": "≃", "propto": "∝",
+	"ll": "≪", "gg": "≫", "prec": "≺", "succ": "≻", "preceq": "⪯",
+	"succeq": "⪰", "subset": "⊂", "supset": "⊃", "subseteq": "⊆",
+	"supseteq": "⊇", "in": "∈", "ni": "∋", "notin": "∉", "perp": "⊥",
+	"parallel": "∥", "mid": "∣", "nmid": "∤", "vdash": "⊢", "dashv": "⊣",
+	"models": "⊨", "doteq": "≐", "asymp": "≍", "bowtie": "⋈",
+	"leftarrow": "←", "gets": "←", "rightarrow": "→", "to": "→",
+	"leftrightarrow": "↔", "Leftarrow": "⇐", "Rightarrow": "⇒",
+	"Leftrightarrow": "⇔", "implies": "⟹", "impliedby": "⟸", "iff": "⟺",
+	"mapsto": "↦", "longrightarrow": "⟶", "longleftarrow": "⟵",
+	"longleftrightarrow": "⟷", "Longrightarrow": "⟹", "Longleftarrow": "⟸",
+	"Longleftrightarrow": "⟺", "longmapsto": "⟼", "uparrow": "↑",
+	"downarrow": "↓", "updownarrow": "↕", "Uparrow": "⇑", "Downarrow": "⇓",
+	"nearrow": "↗", "searrow": "↘", "swarrow": "↙", "nwarrow": "↖",
+	"hookrightarrow": "↪", "hookleftarrow": "↩", "rightleftharpoons": "⇌",
+	"forall": "∀", "exists": "∃", "nexists": "∄", "neg": "¬", "lnot": "¬",
+	"therefore": "∴", "because": "∵", "ldots": "…", "cdots": "⋯",
+	"vdots": "⋮", "ddots": "⋱", "dots": "…", "langle": "⟨", "rangle": "⟩",
+	"lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉", "vert": "|",
+	"lvert": "|", "rvert": "|", "Vert": "‖", "lVert": "‖", "rVert": "‖",
+	"|": "‖", "backslash": "\\", "colon": ":", "angle": "∠",
+	"triangle": "△", "square": "□", "Box": "□", "diamond": "⋄", "top": "⊤",
+	"bot": "⊥", "prime": "′", "{": "{", "}": "}", "lbrace": "{",
+	"rbrace": "}", "lbrack": "[", "rbrack": "]",
+}
+
+// Large operators whose limits go above and below in display math
+var mathLargeOperators = map[string]string{
+	"sum": "∑", "prod": "∏", "coprod": "∐", "bigcup": "⋃", "bigcap": "⋂",
+	"bigoplus": "⨁", "bigotimes": "⨂", "bigvee": "⋁", "bigwedge": "⋀",
+	"bigsqcup": "⨆",
+}
+
+// Integrals keep their limits at the side
+var mathIntegrals = map[string]string{
+	"int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+}
+
+// Named functions set upright
+var mathFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true,
+	"csc": true, "arcsin": true, "arccos": true, "arctan": true,
+	"sinh": true, "cosh": true, "tanh": true, "coth": true, "log": true,
+	"ln": true, "lg": true, "exp": true, "det": true, "dim": true,
+	"ker": true, "deg": true, "gcd": true, "hom": true, "arg": true,
+}
+
+// Functions whose limits go below in display math
+var mathLimitFunctions = map[string]bool{
+	"lim": true, "liminf": true, "limsup": true, "max": true, "min": true,
+	"sup": true, "inf": true, "Pr": true,
+}
+
+// Accents placed over or under their argument: mark and whether it goes
+// below
+var mathAccents = map[string]struct {
+	mark  string
+	under bool
+}{
+	"hat": {"^", false}, "widehat": {"^", false}, "bar": {"¯", false},
+	"overline": {"‾", false}, "vec": {"→", false},
+	"overrightarrow": {"→", false}, "overleftarrow": {"←", false},
+	"dot": {"˙", false}, "ddot": {"¨", false}, "tilde": {"~", false},
+	"widetilde": {"~", false}, "acute": {"´", false}, "grave": {"`", false},
+	"breve": {"˘", false}, "check": {"ˇ", false}, "overbrace": {"⏞", false},
+	"underline": {"_", true}, "underbrace": {"⏟", true},
+}
+
+// Horizontal spaces in em
+var mathSpaces = map[string]string{
+	",": "0.1667em", ":": "0.2222em", ">": "0.2222em", ";": "0.2778em",
+	" ": "0.25em", "quad": "1em", "qquad": "2em", "!": "-0.1667em",
+	"thinspace": "0.1667em", "medspace": "0.2222em", "thickspace": "0.2778em",
+	"enspace": "0.5em",
+}
+
+// Font commands and the variant they select
+var mathVariants = map[string]string{
+	"mathrm": "normal", "textrm": "normal", "mathbf": "bold",
+	"textbf": "bold", "boldsymbol": "bold-italic", "bm": "bold-italic",
+	"mathit": "italic", "textit": "italic", "mathsf": "sans-serif",
+	"textsf": "sans-serif", "mathtt": "monospace", "texttt": "monospace",
+	"mathbb": "double-struck", "mathcal": "script", "mathscr": "script",
+	"mathfrak": "fraktur",
+}
+
+// Environments set as tables, with their delimiters and column alignment
+var mathEnvironments = map[string]struct {
+	open, close string
+	align       string
+}{
+	"matrix": {"", "", ""}, "smallmatrix": {"", "", ""},
+	"pmatrix": {"(", ")", ""}, "bmatrix": {"[", "]", ""},
+	"Bmatrix": {"{", "}", ""}, "vmatrix": {"|", "|", ""},
+	"Vmatrix": {"‖", "‖", ""}, "cases": {"{", "", "left"},
+	"aligned": {"", "", "right left"}, "align": {"", "", "right left"},
+	"align*": {"", "", "right left"}, "split": {"", "", "right left"},
+	"gathered": {"", "", ""}, "gather": {"", "", ""},
+	"gather*": {"", "", ""}, "array": {"", "", ""}, "eqnarray": {"", "", "right center left"},
+	"eqnarray*": {"", "", "right center left"},
+}
+
+// texToMathML converts TeX math to a MathML element. The TeX source is
+// kept as an annotation so it survives copy and paste.
+func texToMathML(tex string, display bool) string {
+	var sb strings.Builder
+	sb.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML"`)
+	if display {
+		sb.WriteString(` display="block"`)
+	}
+	sb.WriteString("><semantics>")
+	writeMathNode(&sb, parseTeX(tex))
+	sb.WriteString(`<annotation encoding="application/x-tex">`)
+	sb.WriteString(html.EscapeString(tex))
+	sb.WriteString("</annotation></semantics></math>")
+	return sb.String()
+}
+
+func writeMathNode(sb *strings.Builder, n *mathNode) {
+	sb.WriteString("<" + n.tag)
+	for _, attr := range n.attrs {
+		sb.WriteString(" " + attr[0] + `="` + html.EscapeString(attr[1]) + `"`)
+	}
+	sb.WriteString(">")
+	if n.isToken() {
+		sb.WriteString(html.EscapeString(n.text))
+	}
+	for _, child := range n.children {
+		writeMathNode(sb, child)
+	}
+	sb.WriteString("</" + n.tag + ">")
+}
+
+// texToText converts TeX math to linear Unicode text for output formats
+// without MathML
+func texToText(tex string) string {
+	return strings.TrimSpace(mathText(parseTeX(tex)))
+}
+
+func mathText(n *mathNode) string {
+	if n.isToken() {
+		return n.text
+	}
+	switch n.tag {
+	case "mspace":
+		return " "
+	case "mfrac":
+		return mathGroupText(n.children[0]) + "/" + mathGroupText(n.children[1])
+	case "msqrt":
+		return "√" + mathGroupText(n.children[0])
+	case "mroot":
+		return mathGroupText(n.children[1]) + "√" + mathGroupText(n.children[0])
+	case "msub":
+		return mathText(n.children[0]) + "_" + mathGroupText(n.children[1])
+	case "msup":
+		return mathText(n.children[0]) + "^" + mathGroupText(n.children[1])
+	case "munder":
+		if hasAttr(n, "accentunder") {
+			return mathText(n.children[0])
+		}
+		return mathText(n.children[0]) + "_" + mathGroupText(n.children[1]) + " "
+	case "mover":
+		if hasAttr(n, "accent") {
+			return mathText(n.children[0]) + n.children[1].text
+		}
+		return mathText(n.children[0]) + "^" + mathGroupText(n.children[1]) + " "
+	case "msubsup":
+		return mathText(n.children[0]) + "_" + mathGroupText(n.children[1]) + "^" + mathGroupText(n.children[2])
+	case "munderover":
+		return mathText(n.children[0]) + "_" + mathGroupText(n.children[1]) + "^" + mathGroupText(n.children[2]) + " "
+	case "mtable":
+		var rows []string
+		for _, row := range n.children {
+			var cells []string
+			for _, cell := range row.children {
+				cells = append(cells, strings.TrimSpace(mathText(cell)))
+			}
+			rows = append(rows, strings.Join(cells, " "))
+		}
+		return strings.Join(rows, "; ")
+	case "annotation":
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, child := range n.children {
+		sb.WriteString(mathText(child))
+	}
+	return sb.String()
+}
+
+// mathGroupText parenthesizes the text of compound expressions
+func mathGroupText(n *mathNode) string {
+	s := strings.TrimSpace(mathText(n))
+	if len([]rune(s)) <= 1 || n.isToken() {
+		return s
+	}
+	return "(" + s + ")"
+}
+
+// texParser turns TeX math into a tree of MathML nodes. It understands the
+// common LaTeX math commands; unknown commands become merror elements
+// instead of failing the conversion.
+type texParser struct {
+	src []rune
+	pos int
+}
+
+func parseTeX(tex string) *mathNode {
+	p := &texParser{src: []rune(tex)}
+	return mathRow(p.parseRow(""))
+}
+
+func (p *texParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *texParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *texParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+	// Comments run to the end of the line
+	if p.peek() == '%' {
+		for !p.eof() && p.src[p.pos] != '\n' {
+			p.pos++
+		}
+		p.skipSpace()
+	}
+}
+
+// peekCommand returns the command at the cursor without consuming it
+func (p *texParser) peekCommand() string {
+	if p.peek() != '\\' || p.pos+1 >= len(p.src) {
+		return ""
+	}
+	end := p.pos + 1
+	for end < len(p.src) && unicode.IsLetter(p.src[end]) {
+		end++
+	}
+	if end == p.pos+1 {
+		end++
+	}
+	return string(p.src[p.pos+1 : end])
+}
+
+// readCommand consumes the command at the cursor
+func (p *texParser) readCommand() string {
+	name := p.peekCommand()
+	p.pos += 1 + len([]rune(name))
+	return name
+}
+
+// parseRow parses expressions until the end of the input, a closing brace,
+// \right, \end, a column separator or a row break. stop names the
+// terminator the caller expects, which is left unconsumed.
+func (p *texParser) parseRow(stop string) []*mathNode {
+	var nodes []*mathNode
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nodes
+		}
+		c := p.peek()
+		switch {
+		case c == '}' && stop == "}":
+			return nodes
+		case c == '}':
+			// Unbalanced brace
+			p.pos++
+			continue
+		case c == '&' && stop == "&":
+			return nodes
+		case c == '\\':
+			switch cmd := p.peekCommand(); {
+			case cmd == "right" && stop == "right":
+				return nodes
+			case cmd == "end" && (stop == "end" || stop == "&"):
+				return nodes
+			case cmd == "\\" && stop == "&":
+				return nodes
+			case cmd == "color":
+				// \color applies to the rest of the group
+				p.readCommand()
+				color := p.readTextArg()
+				rest := mathRow(p.parseRow(stop))
+				nodes = append(nodes, mathElement("mstyle", rest).attr("mathcolor", color))
+				return nodes
+			}
+		}
+
+		atom := p.parseAtom()
+		if atom == nil {
+			continue
+		}
+		nodes = append(nodes, p.parseScripts(atom))
+	}
+}
+
+// parseScripts attaches sub- and superscripts and primes to base
+func (p *texParser) parseScripts(base *mathNode) *mathNode {
+	var sub, sup *mathNode
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case '_':
+			p.pos++
+			sub = p.parseArg()
+			continue
+		case '^':
+			p.pos++
+			sup = p.parseArg()
+			continue
+		case '\'':
+			primes := ""
+			for p.peek() == '\'' {
+				primes += "′"
+				p.pos++
+			}
+			sup = mathToken("mo", primes)
+			continue
+		}
+		if p.peekCommand() == "limits" || p.peekCommand() == "nolimits" {
+			p.readCommand()
+			continue
+		}
+		break
+	}
+	if sub == nil && sup == nil {
+		return base
+	}
+
+	limits := base.tag == "mo" && hasAttr(base, "movablelimits")
+	switch {
+	case sub != nil && sup != nil && limits:
+		return mathElement("munderover", base, sub, sup)
+	case sub != nil && sup != nil:
+		return mathElement("msubsup", base, sub, sup)
+	case sub != nil && limits:
+		return mathElement("munder", base, sub)
+	case sub != nil:
+		return mathElement("msub", base, sub)
+	case limits:
+		return mathElement("mover", base, sup)
+	default:
+		return mathElement("msup", base, sup)
+	}
+}
+
+func hasAttr(n *mathNode, name string) bool {
+	for _, attr := range n.attrs {
+		if attr[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseArg parses a command or script argument: a group or a single atom
+func (p *texParser) parseArg() *mathNode {
+	p.skipSpace()
+	if p.peek() == '{' {
+		p.pos++
+		row := p.parseRow("}")
+		if p.peek() == '}' {
+			p.pos++
+		}
+		return mathRow(row)
+	}
+	atom := p.parseAtom()
+	if atom == nil {
+		return mathElement("mrow")
+	}
+	return atom
+}
+
+// readTextArg reads a braced argument verbatim
+func (p *texParser) readTextArg() string {
+	p.skipSpace()
+	if p.peek() != '{' {
+		if p.eof() {
+			return ""
+		}
+		p.pos++
+		return string(p.src[p.pos-1])
+	}
+	p.pos++
+	start, depth := p.pos, 0
+	for !p.eof() {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				text := string(p.src[start:p.pos])
+				p.pos++
+				return text
+			}
+			depth--
+		}
+		p.pos++
+	}
+	return string(p.src[start:])
+}
+
+// readOptionalArg reads an optional [argument]
+func (p *texParser) readOptionalArg() (*mathNode, bool) {
+	p.skipSpace()
+	if p.peek() != '[' {
+		return nil, false
+	}
+	p.pos++
+	start, depth := p.pos, 0
+	for !p.eof() {
+		switch p.src[p.pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ']':
+			if depth == 0 {
+				inner := &texParser{src: p.src[start:p.pos]}
+				p.pos++
+				return mathRow(inner.parseRow("")), true
+			}
+		}
+		p.pos++
+	}
+	return nil, false
+}
+
+// parseAtom parses one expression without scripts
+func (p *texParser) parseAtom() *mathNode {
+	p.skipSpace()
+	if p.eof() {
+		return nil
+	}
+	c := p.peek()
+
+	switch {
+	case c == '{':
+		p.pos++
+		row := p.parseRow("}")
+		if p.peek() == '}' {
+			p.pos++
+		}
+		return mathRow(row)
+
+	case c == '\\':
+		return p.parseCommand()
+
+	case unicode.IsDigit(c) || (c == '.' && p.pos+1 < len(p.src) && unicode.IsDigit(p.src[p.pos+1])):
+		start := p.pos
+		for !p.eof() && (unicode.IsDigit(p.src[p.pos]) || (p.src[p.pos] == '.' && p.pos+1 < len(p.src) && unicode.IsDigit(p.src[p.pos+1]))) {
+			p.pos++
+		}
+		return mathToken("mn", string(p.src[start:p.pos]))
+
+	case unicode.IsLetter(c):
+		p.pos++
+		return mathToken("mi", string(c))
+
+	case c == '~':
+		p.pos++
+		return mathElement("mspace").attr("width", "0.25em")
+
+	case c == '^' || c == '_':
+		// A script without a base
+		return mathElement("mrow")
+
+	case c == '&':
+		// Column separator outside an environment
+		p.pos++
+		return nil
+	}
+
+	p.pos++
+	switch c {
+	case '-':
+		return mathToken("mo", "−")
+	case '*':
+		return mathToken("mo", "∗")
+	case '(', ')', '[', ']', '|':
+		return mathToken("mo", string(c)).attr("stretchy", "false")
+	}
+	return mathToken("mo", string(c))
+}
+
+// parseCommand parses a backslash command
+func (p *texParser) parseCommand() *mathNode {
+	name := p.readCommand()
+
+	if s, ok := mathIdentifiers[name]; ok {
+		return mathToken("mi", s)
+	}
+	if s, ok := mathUprightIdentifiers[name]; ok {
+		return mathToken("mi", s, [2]string{"mathvariant", "normal"})
+	}
+	if s, ok := mathOperators[name]; ok {
+		return mathToken("mo", s)
+	}
+	if s, ok := mathLargeOperators[name]; ok {
+		return mathToken("mo", s, [2]string{"largeop", "true"}, [2]string{"movablelimits", "true"})
+	}
+	if s, ok := mathIntegrals[name]; ok {
+		return mathToken("mo", s, [2]string{"largeop", "true"})
+	}
+	if mathFunctions[name] {
+		return mathToken("mi", name, [2]string{"mathvariant", "normal"})
+	}
+	if mathLimitFunctions[name] {
+		text := name
+		switch name {
+		case "liminf":
+			text = "lim inf"
+		case "limsup":
+			text = "lim sup"
+		}
+		return mathToken("mo", text, [2]string{"movablelimits", "true"})
+	}
+	if width, ok := mathSpaces[name]; ok {
+		return mathElement("mspace").attr("width", width)
+	}
+	if accent, ok := mathAccents[name]; ok {
+		mark := mathToken("mo", accent.mark)
+		if accent.under {
+			return mathElement("munder", p.parseArg(), mark).attr("accentunder", "true")
+		}
+		return mathElement("mover", p.parseArg(), mark).attr("accent", "true")
+	}
+	if variant, ok := mathVariants[name]; ok {
+		if strings.HasPrefix(name, "text") {
+			return applyMathVariant(mathToken("mtext", p.readTextArg()), variant)
+		}
+		return applyMathVariant(p.parseArg(), variant)
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		num := p.parseArg()
+		den := p.parseArg()
+		return mathElement("mfrac", num, den)
+
+	case "binom", "dbinom", "tbinom":
+		top := p.parseArg()
+		bottom := p.parseArg()
+		return mathElement("mrow",
+			mathToken("mo", "("),
+			mathElement("mfrac", top, bottom).attr("linethickness", "0"),
+			mathToken("mo", ")"),
+		)
+
+	case "sqrt":
+		if index, ok := p.readOptionalArg(); ok {
+			return mathElement("mroot", p.parseArg(), index)
+		}
+		return mathElement("msqrt", p.parseArg())
+
+	case "text", "mbox", "textnormal", "textup":
+		return mathToken("mtext", p.readTextArg())
+
+	case "operatorname":
+		return mathToken("mi", p.readTextArg(), [2]string{"mathvariant", "normal"})
+
+	case "left":
+		open := p.readDelimiter()
+		body := p.parseRow("right")
+		var closing string
+		if p.peekCommand() == "right" {
+			p.readCommand()
+			closing = p.readDelimiter()
+		}
+		nodes := []*mathNode{}
+		if open != "" {
+			nodes = append(nodes, mathToken("mo", open, [2]string{"fence", "true"}, [2]string{"stretchy", "true"}))
+		}
+		nodes = append(nodes, body...)
+		if closing != "" {
+			nodes = append(nodes, mathToken("mo", closing, [2]string{"fence", "true"}, [2]string{"stretchy", "true"}))
+		}
+		return mathElement("mrow", nodes...)
+
+	case "right":
+		// \right without \left
+		p.readDelimiter()
+		return nil
+
+	case "middle", "big", "Big", "bigg", "Bigg", "bigl", "bigr", "Bigl", "Bigr", "biggl", "biggr", "Biggl", "Biggr":
+		return mathToken("mo", p.readDelimiter())
+
+	case "begin":
+		return p.parseEnvironment(p.readTextArg())
+
+	case "not":
+		next := p.parseAtom()
+		if next == nil {
+			return mathToken("mo", "/")
+		}
+		if next.text == "=" {
+			return mathToken("mo", "≠")
+		}
+		if next.isToken() {
+			next.text += "̸"
+		}
+		return next
+
+	case "mod", "bmod":
+		return mathToken("mo", "mod")
+
+	case "pmod":
+		return mathElement("mrow", mathToken("mo", "("), mathToken("mo", "mod"), p.parseArg(), mathToken("mo", ")"))
+
+	case "textcolor":
+		color := p.readTextArg()
+		return mathElement("mstyle", p.parseArg()).attr("mathcolor", color)
+
+	case "mathrel", "mathbin", "mathop", "mathord", "mathpunct", "mathopen", "mathclose", "displaystyle", "textstyle", "scriptstyle":
+		if strings.HasPrefix(name, "math") {
+			return p.parseArg()
+		}
+		return nil
+
+	case "$", "%", "&", "#", "_":
+		return mathToken("mo", name)
+
+	case "\\":
+		// Row break outside an environment
+		p.readOptionalArg()
+		return nil
+	}
+
+	return mathElement("merror", mathToken("mtext", "\\"+name))
+}
+
+// readDelimiter reads the delimiter after \left, \right or \big
+func (p *texParser) readDelimiter() string {
+	p.skipSpace()
+	if p.eof() {
+		return ""
+	}
+	if p.peek() == '\\' {
+		name := p.readCommand()
+		if s, ok := mathOperators[name]; ok {
+			return s
+		}
+		return ""
+	}
+	c := p.peek()
+	p.pos++
+	if c == '.' {
+		return ""
+	}
+	return string(c)
+}
+
+// parseEnvironment parses \begin{name} ... \end{name} into a table
+func (p *texParser) parseEnvironment(name string) *mathNode {
+	env, ok := mathEnvironments[name]
+	if !ok {
+		return mathElement("merror", mathToken("mtext", "\\begin{"+name+"}"))
+	}
+	if name == "array" {
+		// Column specification
+		p.readTextArg()
+	}
+
+	table := mathElement("mtable")
+	if env.align != "" {
+		table.attr("columnalign", env.align)
+	}
+	row := mathElement("mtr")
+	for {
+		cell := p.parseRow("&")
+		row.children = append(row.children, mathElement("mtd", mathRow(cell)))
+
+		if p.eof() {
+			break
+		}
+		if p.peek() == '&' {
+			p.pos++
+			continue
+		}
+		cmd := p.readCommand()
+		if cmd == "\\" {
+			// Optional vertical space after a row break
+			p.readOptionalArg()
+			table.children = append(table.children, row)
+			row = mathElement("mtr")
+			continue
+		}
+		// \end{name}
+		p.readTextArg()
+		break
+	}
+	if !(len(row.children) == 1 && len(row.children[0].children) == 1 && row.children[0].children[0].tag == "mrow" && len(row.children[0].children[0].children) == 0) {
+		table.children = append(table.children, row)
+	}
+
+	if env.open == "" && env.close == "" {
+		return table
+	}
+	nodes := []*mathNode{}
+	if env.open != "" {
+		nodes = append(nodes, mathToken("mo", env.open, [2]string{"fence", "true"}, [2]string{"stretchy", "true"}))
+	}
+	nodes = append(nodes, table)
+	if env.close != "" {
+		nodes = append(nodes, mathToken("mo", env.close, [2]string{"fence", "true"}, [2]string{"stretchy", "true"}))
+	}
+	return mathElement("mrow", nodes...)
+}
+
+// applyMathVariant restyles the tokens below n. Double-struck, script and
+// fraktur letters map to their Unicode characters; the other variants are
+// set with CSS, which renders everywhere unlike mathvariant.
+func applyMathVariant(n *mathNode, variant string) *mathNode {
+	if n == nil {
+		return nil
+	}
+	if !n.isToken() {
+		for _, child := range n.children {
+			applyMathVariant(child, variant)
+		}
+		return n
+	}
+
+	if letters, ok := mathLetterVariants[variant]; ok {
+		var sb strings.Builder
+		for _, r := range n.text {
+			if s, ok := letters[r]; ok {
+				sb.WriteString(s)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+		n.text = sb.String()
+		if n.tag == "mi" {
+			n.attr("mathvariant", "normal")
+		}
+		return n
+	}
+
+	switch variant {
+	case "normal":
+		if n.tag == "mi" {
+			n.attr("mathvariant", "normal")
+		}
+	case "bold":
+		if n.tag == "mi" {
+			n.attr("mathvariant", "normal")
+		}
+		n.attr("style", "font-weight: bold")
+	case "bold-italic":
+		n.attr("style", "font-weight: bold")
+	case "italic":
+		n.attr("style", "font-style: italic")
+	case "sans-serif":
+		if n.tag == "mi" {
+			n.attr("mathvariant", "normal")
+		}
+		n.attr("style", "font-family: sans-serif")
+	case "monospace":
+		if n.tag == "mi" {
+			n.attr("mathvariant", "normal")
+		}
+		n.attr("style", "font-family: monospace")
+	}
+	return n
+}
+
+// mathLetterVariants maps letters to their Unicode mathematical forms
+var mathLetterVariants = map[string]map[rune]string{
+	"double-struck": mathAlphabet(0x1D538, 0x1D552, map[rune]string{
+		'C': "ℂ", 'H': "ℍ", 'N': "ℕ", 'P': "ℙ", 'Q': "ℚ", 'R': "ℝ", 'Z': "ℤ",
+	}, 0x1D7D8),
+	"script": mathAlphabet(0x1D49C, 0x1D4B6, map[rune]string{
+		'B': "ℬ", 'E': "ℰ", 'F': "ℱ", 'H': "ℋ", 'I': "ℐ", 'L': "ℒ", 'M': "ℳ",
+		'R': "ℛ", 'e': "ℯ", 'g': "ℊ", 'o': "ℴ",
+	}, 0),
+	"fraktur": mathAlphabet(0x1D504, 0x1D51E, map[rune]string{
+		'C': "ℭ", 'H': "ℌ", 'I': "ℑ", 'R': "ℜ", 'Z': "ℨ",
+	}, 0),
+}
+
+// mathAlphabet builds a letter map from the first capital, first small
+// letter and first digit of a Unicode math alphabet; exceptions hold the
+// letters that live in the Letterlike Symbols block instead
+func mathAlphabet(capital, small rune, exceptions map[rune]string, digit rune) map[rune]string {
+	letters := make(map[rune]string)
+	for i := rune(0); i < 26; i++ {
+		letters['A'+i] = string(capital + i)
+		letters['a'+i] = string(small + i)
+	}
+	if digit != 0 {
+		for i := rune(0); i < 10; i++ {
+			letters['0'+i] = string(digit + i)
+		}
+	}
+	for r, s := range exceptions {
+		letters[r] = s
+	}
+	return letters
+}
+
+// mathPrintCSS lays out MathML in browsers without MathML support, such as
+// the WebKit inside wkhtmltopdf. It is only added to pages printed to PDF,
+// because it would override native MathML layout in current browsers.
+const mathPrintCSS = `
+		math {
+			display: inline-block;
+			font-family: "Cambria Math", "STIX Two Math", "Latin Modern Math", "DejaVu Serif", serif;
+			font-style: normal;
+			text-indent: 0;
+			line-height: normal;
+		}
+		math[display="block"] {
+			display: block;
+			text-align: center;
+			margin: 1em 0;
+		}
+		annotation {
+			display: none;
+		}
+		mi {
+			font-style: italic;
+		}
+		mi[mathvariant="normal"], mn, mo, mtext {
+			font-style: normal;
+		}
+		mo {
+			padding: 0 0.15em;
+		}
+		mo[fence], mo[stretchy] {
+			padding: 0;
+		}
+		mfrac {
+			display: inline-block;
+			vertical-align: middle;
+			text-align: center;
+			padding: 0 0.1em;
+		}
+		mfrac > * {
+			display: block;
+		}
+		mfrac > :first-child {
+			border-bottom: 1px solid;
+		}
+		mfrac[linethickness="0"] > :first-child {
+			border-bottom: 0;
+		}
+		msup > :last-child, msubsup > :last-child {
+			font-size: 75%;
+			vertical-align: super;
+		}
+		msub > :last-child, msubsup > :nth-child(2) {
+			font-size: 75%;
+			vertical-align: sub;
+		}
+		msqrt::before, mroot::before {
+			content: "\221A";
+		}
+		msqrt > *, mroot > :first-child {
+			border-top: 1px solid;
+		}
+		mroot > :last-child {
+			font-size: 60%;
+			vertical-align: super;
+		}
+		munder, mover, munderover {
+			display: inline-table;
+			vertical-align: middle;
+			text-align: center;
+		}
+		munder > *, mover > *, munderover > * {
+			display: table-row-group;
+			line-height: 1;
+		}
+		mover > :last-child, munderover > :last-child {
+			display: table-header-group;
+			font-size: 75%;
+		}
+		munder > :last-child, munderover > :nth-child(2) {
+			display: table-footer-group;
+			font-size: 75%;
+		}
+		mover[accent] > :last-child, munder[accentunder] > :last-child {
+			font-size: 100%;
+		}
+		mtable {
+			display: inline-table;
+			vertical-align: middle;
+		}
+		mtr {
+			display: table-row;
+		}
+		mtd {
+			display: table-cell;
+			padding: 0.2em 0.5em;
+		}
+		merror {
+			color: #dc3545;
+		}`
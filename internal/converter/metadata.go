@@ -0,0 +1,282 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// DefaultCreator is recorded as the creating application of every document
+const DefaultCreator = "markdown-to-html"
+
+// Front matter keys read into document metadata
+const (
+	metaTitle       = "title"
+	metaAuthor      = "author"
+	metaSubject     = "subject"
+	metaDescription = "description"
+	metaKeywords    = "keywords"
+	metaDate        = "date"
+	metaLang        = "lang"
+	metaLanguage    = "language"
+)
+
+// Metadata is the document information stored in PDF and HTML output
+type Metadata struct {
+	Title    string
+	Author   string
+	Subject  string
+	Keywords []string
+	Language string    // BCP 47 tag such as "tr" or "de-CH"
+	Creator  string    // Defaults to DefaultCreator
+	Created  time.Time // Defaults to the time of conversion
+}
+
+// dateLayouts are the front matter date formats understood besides YAML timestamps
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02.01.2006",
+}
+
+// resolveMetadata merges metadata for a document: non-empty configured
+// fields win over front matter, and the title falls back to the first level
+// 1 heading
+func resolveMetadata(configured *Metadata, doc *document) Metadata {
+	var md Metadata
+
+	md.Title = metaString(doc.meta[metaTitle])
+	md.Author = strings.Join(metaList(doc.meta[metaAuthor]), ", ")
+	md.Subject = metaString(doc.meta[metaSubject])
+	if md.Subject == "" {
+		md.Subject = metaString(doc.meta[metaDescription])
+	}
+	md.Keywords = metaList(doc.meta[metaKeywords])
+	md.Created = metaTime(doc.meta[metaDate])
+	md.Language = metaLanguageTag(doc.meta)
+
+	if configured != nil {
+		if configured.Title != "" {
+			md.Title = configured.Title
+		}
+		if configured.Author != "" {
+			md.Author = configured.Author
+		}
+		if configured.Subject != "" {
+			md.Subject = configured.Subject
+		}
+		if len(configured.Keywords) > 0 {
+			md.Keywords = configured.Keywords
+		}
+		if configured.Language != "" {
+			md.Language = configured.Language
+		}
+		md.Creator = configured.Creator
+		if !configured.Created.IsZero() {
+			md.Created = configured.Created
+		}
+	}
+
+	if md.Title == "" {
+		md.Title = firstHeading(doc)
+	}
+	if md.Creator == "" {
+		md.Creator = DefaultCreator
+	}
+	if md.Created.IsZero() {
+		md.Created = time.Now()
+	}
+	return md
+}
+
+// withTitle returns a copy of configured whose title defaults to title
+func withTitle(configured *Metadata, title string) *Metadata {
+	var md Metadata
+	if configured != nil {
+		md = *configured
+	}
+	if md.Title == "" {
+		md.Title = title
+	}
+	return &md
+}
+
+// firstHeading returns the text of the first level 1 heading
+func firstHeading(doc *document) string {
+	var title string
+	_ = ast.Walk(doc.root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if heading, ok := n.(*ast.Heading); ok && entering && heading.Level == 1 {
+			title = string(heading.Text(doc.source))
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return title
+}
+
+func metaString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+// metaList accepts a comma separated string or a list
+func metaList(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s := metaString(item); s != "" {
+				items = append(items, s)
+			}
+		}
+	}
+	return items
+}
+
+// metaLanguageTag reads the document language from the lang or language
+// front matter key
+func metaLanguageTag(meta map[string]interface{}) string {
+	if lang := metaString(meta[metaLang]); lang != "" {
+		return lang
+	}
+	return metaString(meta[metaLanguage])
+}
+
+func metaTime(value interface{}) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case string:
+		for _, layout := range dateLayouts {
+			if t, err := time.ParseInLocation(layout, strings.TrimSpace(v), time.Local); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// metadataHTML returns the escaped <title> text and meta tags for the HTML head
+func metadataHTML(md Metadata) (string, string) {
+	var head strings.Builder
+	tag := func(name, content string) {
+		if content != "" {
+			fmt.Fprintf(&head, "\n    <meta name=\"%s\" content=\"%s\">", name, html.EscapeString(content))
+		}
+	}
+	tag("author", md.Author)
+	tag("description", md.Subject)
+	tag("keywords", strings.Join(md.Keywords, ", "))
+	tag("generator", md.Creator)
+
+	return html.EscapeString(md.Title), head.String()
+}
+
+// configuredLanguage returns the language set in configured, if any
+func configuredLanguage(configured *Metadata) string {
+	if configured == nil {
+		return ""
+	}
+	return configured.Language
+}
+
+// languageHTML returns the escaped language for the lang attribute
+func languageHTML(md Metadata) string {
+	return html.EscapeString(md.Language)
+}
+
+var (
+	trailerRoot = regexp.MustCompile(`/Root\s+(\d+\s+\d+\s+R)`)
+	trailerSize = regexp.MustCompile(`/Size\s+(\d+)`)
+	startXref   = regexp.MustCompile(`startxref\s+(\d+)\s+%%EOF\s*$`)
+)
+
+// withInfo appends an incremental update that replaces the document
+// information dictionary of a PDF. wkhtmltopdf can only set the title, so
+// this adds the remaining fields. PDFs it cannot parse, such as ones using
+// cross-reference streams, are returned unchanged.
+func withInfo(pdf []byte, md Metadata) []byte {
+	trailerAt := bytes.LastIndex(pdf, []byte("trailer"))
+	if trailerAt < 0 {
+		return pdf
+	}
+	trailer := pdf[trailerAt:]
+	root := trailerRoot.FindSubmatch(trailer)
+	size := trailerSize.FindSubmatch(trailer)
+	prev := startXref.FindSubmatch(trailer)
+	if root == nil || size == nil || prev == nil {
+		return pdf
+	}
+
+	var object int
+	fmt.Sscan(string(size[1]), &object)
+
+	var out bytes.Buffer
+	out.Write(pdf)
+	if !bytes.HasSuffix(pdf, []byte("\n")) {
+		out.WriteByte('\n')
+	}
+
+	offset := out.Len()
+	fmt.Fprintf(&out, "%d 0 obj\n<<", object)
+	entry := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&out, " /%s %s", key, pdfTextString(value))
+		}
+	}
+	entry("Title", md.Title)
+	entry("Author", md.Author)
+	entry("Subject", md.Subject)
+	entry("Keywords", strings.Join(md.Keywords, ", "))
+	entry("Creator", md.Creator)
+	entry("Producer", "wkhtmltopdf")
+	if !md.Created.IsZero() {
+		fmt.Fprintf(&out, " /CreationDate (%s)", pdfDate(md.Created))
+	}
+	out.WriteString(" >>\nendobj\n")
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n%d 1\n%010d 00000 n \n", object, offset)
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %s /Info %d 0 R /Prev %s >>\n", object+1, root[1], object, prev[1])
+	fmt.Fprintf(&out, "startxref\n%d\n%%%%EOF\n", xref)
+
+	return out.Bytes()
+}
+
+// pdfTextString encodes s as a UTF-16BE hex string with byte order mark
+func pdfTextString(s string) string {
+	var sb strings.Builder
+	sb.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&sb, "%04X", unit)
+	}
+	sb.WriteString(">")
+	return sb.String()
+}
+
+// pdfDate formats t as a PDF date string
+func pdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("D:%s%c%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
@@ -0,0 +1,85 @@
+package converter
+
+import (
+	"time"
+
+	"github.com/yuin/goldmark"
+)
+
+// Built-in page themes
+const (
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+)
+
+// Themes returns the names of the built-in page themes
+func Themes() []string {
+	return []string{ThemeLight, ThemeDark}
+}
+
+// Options configures a conversion. The zero value renders the light theme
+// with the default fonts.
+type Options struct {
+	// Theme is ThemeLight or ThemeDark; anything else renders the light theme
+	Theme string
+
+	// Fonts selects custom typefaces; nil keeps the defaults
+	Fonts *FontConfig
+
+	// BreakBefore lists heading levels that always start a new PDF page.
+	// Front matter can add levels with page-break-before.
+	BreakBefore []int
+
+	// Watermark stamps every page; nil falls back to the front matter
+	// watermark or status entries
+	Watermark *Watermark
+
+	// Metadata sets the document information; empty fields fall back to
+	// the front matter title, author, subject, keywords and date
+	Metadata *Metadata
+
+	// Extensions enables built-in or registered extensions by name; see
+	// ExtensionNames
+	Extensions []string
+
+	// Includes enables {{< include "path" >}} directives and code blocks
+	// filled from files; nil leaves both as written. Its root also bounds
+	// front matter watermark images, which are ignored when it is nil.
+	Includes *Includes
+
+	// CopyButtons adds a copy button to every fenced code block in HTML
+	// output; blocks can opt in or out with copy and nocopy in their info
+	// string
+	CopyButtons bool
+
+	// References autolinks issue numbers, mentions and commit SHAs with
+	// its URL templates; nil leaves them as text
+	References *References
+
+	// Extenders adds custom goldmark extensions, such as ones built with
+	// WithASTTransformer or WithNodeRenderer. Node renderers only affect
+	// HTML output and the wkhtmltopdf engine.
+	Extenders []goldmark.Extender
+
+	// Filters are external commands that rewrite the parsed document as
+	// JSON between parsing and rendering, in order. Each command is split
+	// on spaces and gets the output format as its last argument.
+	Filters []string
+
+	// Diagrams maps fence languages such as "dot" to commands that read
+	// the diagram source on stdin and write SVG to stdout; they are split
+	// on spaces like Filters. Commands are opt-in: nil or an empty map
+	// runs none and keeps diagram fences as code, and DefaultDiagrams()
+	// enables Graphviz and PlantUML. A fence whose command fails stays code
+	// too. Chart fences are drawn in Go and need no command.
+	Diagrams map[string]string
+
+	// DiagramCache is the directory rendered diagrams are cached in, keyed
+	// by a hash of the command and source; empty uses DefaultDiagramCache
+	DiagramCache string
+
+	// Timeout limits the time spent on one document; zero means no limit.
+	// It applies to the context-aware entry points and the wrappers built
+	// on them.
+	Timeout time.Duration
+}
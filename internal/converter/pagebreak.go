@@ -0,0 +1,277 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	meta "github.com/yuin/goldmark-meta"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Classes added to nodes by the page break transformer
+const (
+	classBreakBefore  = "page-break-before"
+	classFigure       = "figure"
+	classKeepTogether = "keep-together"
+)
+
+// Front matter keys controlling page breaks
+const (
+	metaBreakBefore  = "page-break-before"
+	metaKeepTogether = "keep-together"
+)
+
+// pageBreakCSS implements page breaks and keep-together rules for
+// wkhtmltopdf and browser printing
+const pageBreakCSS = `
+		.page-break {
+			height: 0;
+			margin: 0;
+			border: 0;
+			page-break-after: always;
+			break-after: page;
+		}
+		.page-break-before {
+			page-break-before: always;
+			break-before: page;
+		}
+		.markdown-content h1,
+		.markdown-content h2,
+		.markdown-content h3,
+		.markdown-content h4,
+		.markdown-content h5,
+		.markdown-content h6 {
+			page-break-after: avoid;
+			break-after: avoid;
+		}
+		.keep-together table,
+		.keep-together pre,
+		.keep-together blockquote,
+		.keep-together img,
+		.keep-together .figure {
+			page-break-inside: avoid;
+			break-inside: avoid;
+		}
+		.keep-together tr {
+			page-break-inside: avoid;
+			break-inside: avoid;
+		}`
+
+// KindPageBreak is the node kind of an explicit page break
+var KindPageBreak = ast.NewNodeKind("PageBreak")
+
+// PageBreak is an explicit page break from a marker in the source
+type PageBreak struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node
+func (n *PageBreak) Kind() ast.NodeKind {
+	return KindPageBreak
+}
+
+// Dump implements ast.Node
+func (n *PageBreak) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// pageBreakMarker matches <!-- pagebreak -->, <!-- page-break --> and <!-- newpage -->
+var pageBreakMarker = regexp.MustCompile(`(?i)^<!--\s*(page-?break|new-?page)\s*-->$`)
+
+// latexBreakMarker matches a \newpage or \pagebreak line
+var latexBreakMarker = regexp.MustCompile(`^\\(newpage|pagebreak|clearpage)$`)
+
+// pageBreaks converts page break markers into PageBreak nodes and marks
+// headings that start a new page
+type pageBreaks struct {
+	breakBefore []int
+}
+
+// Transform implements parser.ASTTransformer
+func (t *pageBreaks) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	levels := append([]int(nil), t.breakBefore...)
+	if value, ok := meta.Get(pc)[metaBreakBefore]; ok {
+		if fromMeta, err := headingLevelsFromMeta(value); err == nil {
+			levels = append(levels, fromMeta...)
+		}
+	}
+
+	var markers []ast.Node
+	first := true
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := n.(type) {
+		case *ast.HTMLBlock:
+			if pageBreakMarker.MatchString(strings.TrimSpace(string(blockText(n, source)))) {
+				markers = append(markers, n)
+			}
+			return ast.WalkSkipChildren, nil
+
+		case *ast.Paragraph:
+			if latexBreakMarker.MatchString(strings.TrimSpace(string(blockText(n, source)))) {
+				markers = append(markers, n)
+			} else if isFigure(n) {
+				addClass(n, classFigure)
+			}
+
+		case *ast.Heading:
+			// A break before the very first block would leave an empty page
+			if !first && containsLevel(levels, n.Level) {
+				addClass(n, classBreakBefore)
+			}
+		}
+
+		if n.Type() == ast.TypeBlock && n.Kind() != ast.KindDocument {
+			first = false
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, n := range markers {
+		n.Parent().ReplaceChild(n.Parent(), n, &PageBreak{})
+	}
+}
+
+// keepTogether reports whether tables, code blocks and figures should avoid
+// page breaks; it is on unless front matter sets keep-together: false
+func keepTogether(metadata map[string]interface{}) bool {
+	keep, ok := metadata[metaKeepTogether].(bool)
+	return !ok || keep
+}
+
+// blockText returns the raw source lines of a block node
+func blockText(n ast.Node, source []byte) []byte {
+	var buf []byte
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		buf = append(buf, line.Value(source)...)
+	}
+	return buf
+}
+
+// isFigure reports whether a paragraph holds nothing but a single image
+func isFigure(n *ast.Paragraph) bool {
+	child := n.FirstChild()
+	return child != nil && child == n.LastChild() && child.Kind() == ast.KindImage
+}
+
+// addClass appends class to the class attribute of n
+func addClass(n ast.Node, class string) {
+	if existing, ok := n.AttributeString("class"); ok {
+		if s, ok := existing.([]byte); ok {
+			class = string(s) + " " + class
+		} else if s, ok := existing.(string); ok {
+			class = s + " " + class
+		}
+	}
+	n.SetAttributeString("class", []byte(class))
+}
+
+// hasClass reports whether the class attribute of n contains class
+func hasClass(n ast.Node, class string) bool {
+	existing, ok := n.AttributeString("class")
+	if !ok {
+		return false
+	}
+	var classes string
+	switch v := existing.(type) {
+	case []byte:
+		classes = string(v)
+	case string:
+		classes = v
+	}
+	for _, c := range strings.Fields(classes) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func containsLevel(levels []int, level int) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseHeadingLevels parses a comma separated list of heading levels such
+// as "h1,h2" or "1,2"
+func ParseHeadingLevels(s string) ([]int, error) {
+	var levels []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		level, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(part), "h"))
+		if err != nil || level < 1 || level > 6 {
+			return nil, fmt.Errorf("invalid heading level %q", part)
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// headingLevelsFromMeta accepts a string ("h1" or "h1, h2"), a number or a list
+func headingLevelsFromMeta(value interface{}) ([]int, error) {
+	switch v := value.(type) {
+	case string:
+		return ParseHeadingLevels(v)
+	case int:
+		return ParseHeadingLevels(strconv.Itoa(v))
+	case []interface{}:
+		var levels []int
+		for _, item := range v {
+			more, err := headingLevelsFromMeta(item)
+			if err != nil {
+				return nil, err
+			}
+			levels = append(levels, more...)
+		}
+		return levels, nil
+	}
+	return nil, fmt.Errorf("invalid heading levels %v", value)
+}
+
+// pageBreakHTMLRenderer renders PageBreak nodes
+type pageBreakHTMLRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *pageBreakHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindPageBreak, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			_, _ = w.WriteString("<div class=\"page-break\"></div>\n")
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// pageBreakExtension wires the page break transformer and renderer into goldmark
+type pageBreakExtension struct {
+	breakBefore []int
+}
+
+// Extend implements goldmark.Extender
+func (e *pageBreakExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&pageBreaks{breakBefore: e.breakBefore}, 500),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&pageBreakHTMLRenderer{}, 500),
+	))
+}
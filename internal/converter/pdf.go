@@ -1,7 +1,10 @@
 package converter
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -10,27 +13,122 @@ import (
 	"github.com/SebastiaanKlippert/go-wkhtmltopdf"
 )
 
+// PDF engine names accepted by NewPDFRenderer
+const (
+	EngineWkhtmltopdf = "wkhtmltopdf"
+	EngineNative      = "native"
+)
+
+// PDFRenderer renders markdown content into a PDF document
+type PDFRenderer interface {
+	// RenderPDF writes the PDF for markdown to w. Implementations must not
+	// write anything to w when rendering fails.
+	RenderPDF(w io.Writer, markdown string, opts Options) error
+}
+
+// NewPDFRenderer returns the PDF renderer for the named engine
+func NewPDFRenderer(engine string) (PDFRenderer, error) {
+	switch engine {
+	case EngineWkhtmltopdf, "":
+		return &WkhtmltopdfRenderer{}, nil
+	case EngineNative:
+		return &NativeRenderer{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported PDF engine %q (supported: %s, %s)", engine, EngineWkhtmltopdf, EngineNative)
+	}
+}
+
 // ConvertToPDF converts markdown content to PDF
 func ConvertToPDF(markdown string, outputPath string, theme string) error {
+	return ConvertToPDFWith(&WkhtmltopdfRenderer{}, markdown, outputPath, Options{Theme: theme})
+}
+
+// ConvertToPDFWith converts markdown content to a PDF file using the given
+// renderer. A failed conversion never leaves a truncated file behind.
+func ConvertToPDFWith(renderer PDFRenderer, markdown string, outputPath string, opts Options) error {
+	return ConvertToPDFContext(context.Background(), renderer, markdown, outputPath, opts)
+}
+
+// ConvertToPDFWriter converts markdown content to PDF with wkhtmltopdf and
+// writes the result to w. Nothing is written to w unless the conversion
+// succeeds. Every call uses its own temporary workspace, so concurrent
+// conversions never share files.
+func ConvertToPDFWriter(w io.Writer, markdown string, theme string) error {
+	return (&WkhtmltopdfRenderer{}).RenderPDF(w, markdown, Options{Theme: theme})
+}
+
+// WkhtmltopdfRenderer renders PDFs by printing the HTML output with wkhtmltopdf
+type WkhtmltopdfRenderer struct{}
+
+// RenderPDF implements PDFRenderer
+func (r *WkhtmltopdfRenderer) RenderPDF(w io.Writer, markdown string, opts Options) error {
+	return r.RenderPDFContext(context.Background(), w, markdown, opts)
+}
+
+// RenderPDFContext implements ContextPDFRenderer; wkhtmltopdf is killed when
+// ctx is done
+func (r *WkhtmltopdfRenderer) RenderPDFContext(ctx context.Context, w io.Writer, markdown string, opts Options) error {
 	// First convert markdown to HTML
-	html, err := ConvertToHTML(markdown, theme)
+	opts = linkedFonts(opts)
+	doc, err := parseDocument(markdown, opts)
 	if err != nil {
-		return fmt.Errorf("failed to convert markdown to HTML: %w", err)
+		return err
+	}
+	if err := applyFilters(ctx, doc, opts, FilterFormatPDF); err != nil {
+		return err
 	}
+	if err := renderDiagrams(ctx, doc, opts); err != nil {
+		return err
+	}
+	return r.printDocument(ctx, w, doc, opts, nil)
+}
+
+// printDocument prints a parsed document with wkhtmltopdf
+func (r *WkhtmltopdfRenderer) printDocument(ctx context.Context, w io.Writer, doc *document, opts Options, configure func(workDir string, pdfg *wkhtmltopdf.PDFGenerator, page *wkhtmltopdf.Page) error) error {
+	writePage := func(f io.Writer) error {
+		if err := writePrintHTML(f, doc, opts); err != nil {
+			return fmt.Errorf("failed to convert markdown to HTML: %w", err)
+		}
+		return nil
+	}
+	return r.printHTML(ctx, w, writePage, resolveMetadata(opts.Metadata, doc), configure)
+}
+
+// linkedFonts makes fonts load from disk through local file access rather
+// than inlined, which wkhtmltopdf handles poorly
+func linkedFonts(opts Options) Options {
+	if opts.Fonts != nil {
+		fonts := *opts.Fonts
+		fonts.Embed = false
+		opts.Fonts = &fonts
+	}
+	return opts
+}
 
-	// Create temporary HTML file
-	tempHTML := filepath.Join(os.TempDir(), "temp_markdown.html")
-	err = os.WriteFile(tempHTML, []byte(html), 0644)
+// printHTML prints the HTML page produced by writePage to PDF and stores
+// info as its document information. configure, if not nil, can adjust the generator and page
+// before rendering; it receives the job's private working directory for any
+// extra files.
+func (r *WkhtmltopdfRenderer) printHTML(ctx context.Context, w io.Writer, writePage func(io.Writer) error, info Metadata, configure func(workDir string, pdfg *wkhtmltopdf.PDFGenerator, page *wkhtmltopdf.Page) error) error {
+	// Create a private workspace for this job
+	workDir, err := os.MkdirTemp("", "md2pdf-*")
 	if err != nil {
-		return fmt.Errorf("failed to create temporary HTML file: %w", err)
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	// wkhtmltopdf needs a file on disk to resolve local resources
+	tempHTML := filepath.Join(workDir, "document.html")
+	if err := writeFile(tempHTML, writePage); err != nil {
+		return err
 	}
-	defer os.Remove(tempHTML)
 
-	// Set wkhtmltopdf path if not in PATH
-	wkhtmltopdfPath := getWkhtmltopdfPath()
-	if wkhtmltopdfPath != "" {
-		wkhtmltopdf.SetPath(wkhtmltopdfPath)
+	// Point go-wkhtmltopdf at the discovered binary
+	wkhtmltopdfPath, err := FindWkhtmltopdf()
+	if err != nil {
+		return err
 	}
+	wkhtmltopdf.SetPath(wkhtmltopdfPath)
 
 	// Create PDF generator
 	pdfg, err := wkhtmltopdf.NewPDFGenerator()
@@ -47,37 +145,62 @@ func ConvertToPDF(markdown string, outputPath string, theme string) error {
 	pdfg.MarginBottom.Set(20)
 	pdfg.MarginLeft.Set(20)
 	pdfg.MarginRight.Set(20)
+	if info.Title != "" {
+		pdfg.Title.Set(info.Title)
+	}
 
 	// Add page
 	page := wkhtmltopdf.NewPage(tempHTML)
 	page.EnableLocalFileAccess.Set(true)
 	page.LoadErrorHandling.Set("ignore")
 	page.LoadMediaErrorHandling.Set("ignore")
-	
+
 	// Set page options for better rendering
 	page.Zoom.Set(1.0)
 	page.JavascriptDelay.Set(1000) // Wait for JS to load
-	
+
 	pdfg.AddPage(page)
 
-	// Generate PDF
-	err = pdfg.Create()
+	if configure != nil {
+		if err := configure(workDir, pdfg, page); err != nil {
+			return err
+		}
+	}
+
+	// Generate PDF; wkhtmltopdf writes to stdout, which the generator
+	// collects in its own buffer
+	err = pdfg.CreateContext(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to generate PDF: %w", err)
 	}
 
-	// Ensure output directory exists
-	outputDir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	// Copy PDF bytes to the caller
+	if _, err := w.Write(withInfo(pdfg.Bytes(), info)); err != nil {
+		return fmt.Errorf("failed to write PDF output: %w", err)
 	}
 
-	// Write PDF to file
-	err = pdfg.WriteFile(outputPath)
+	return nil
+}
+
+// writeFile creates path and fills it with write
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("failed to write PDF file: %w", err)
+		return fmt.Errorf("failed to create temporary HTML file: %w", err)
 	}
 
+	buffered := bufio.NewWriter(file)
+	if err := write(buffered); err != nil {
+		file.Close()
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write temporary HTML file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary HTML file: %w", err)
+	}
 	return nil
 }
 
@@ -93,54 +216,6 @@ func ConvertMarkdownFileToPDF(inputFile string, outputFile string, theme string)
 	return ConvertToPDF(content, outputFile, theme)
 }
 
-// IsWkhtmltopdfInstalled checks if wkhtmltopdf is installed
-func IsWkhtmltopdfInstalled() bool {
-	// Check common installation paths
-	paths := []string{
-		"/usr/local/bin/wkhtmltopdf",
-		"C:\\Program Files\\wkhtmltopdf\\bin\\wkhtmltopdf.exe",
-		"C:\\Program Files (x86)\\wkhtmltopdf\\bin\\wkhtmltopdf.exe",
-		"wkhtmltopdf", // Check if it's in PATH
-	}
-	
-	for _, path := range paths {
-		if path == "wkhtmltopdf" {
-			// Check if it's in PATH
-			if _, err := os.Stat("C:\\Program Files\\wkhtmltopdf\\bin\\wkhtmltopdf.exe"); err == nil {
-				return true
-			}
-			if _, err := os.Stat("C:\\Program Files (x86)\\wkhtmltopdf\\bin\\wkhtmltopdf.exe"); err == nil {
-				return true
-			}
-		} else {
-			if _, err := os.Stat(path); err == nil {
-				return true
-			}
-		}
-	}
-	
-	return false
-}
-
-// getWkhtmltopdfPath returns the path to wkhtmltopdf executable
-func getWkhtmltopdfPath() string {
-	// Check common installation paths
-	paths := []string{
-		"C:\\Program Files\\wkhtmltopdf\\bin\\wkhtmltopdf.exe",
-		"C:\\Program Files (x86)\\wkhtmltopdf\\bin\\wkhtmltopdf.exe",
-		"/usr/local/bin/wkhtmltopdf",
-		"/usr/bin/wkhtmltopdf",
-	}
-	
-	for _, path := range paths {
-		if _, err := os.Stat(path); err == nil {
-			return path
-		}
-	}
-	
-	return ""
-}
-
 // GetWkhtmltopdfInstallInstructions returns installation instructions
 func GetWkhtmltopdfInstallInstructions() string {
 	return `wkhtmltopdf is required for PDF generation.
@@ -161,5 +236,8 @@ sudo apt-get install wkhtmltopdf
 Linux (CentOS/RHEL):
 sudo yum install wkhtmltopdf
 
+If wkhtmltopdf is installed in a custom location, pass --wkhtmltopdf <path>
+or set the WKHTMLTOPDF_BIN environment variable.
+
 After installation, restart your terminal and try again.`
 }
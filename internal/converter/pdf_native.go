@@ -0,0 +1,1420 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+// NativeRenderer renders PDFs in pure Go by walking the goldmark AST, so no
+// external binary is needed. Styling is simpler than the HTML output.
+type NativeRenderer struct{}
+
+// RenderPDF implements PDFRenderer
+func (r *NativeRenderer) RenderPDF(w io.Writer, markdown string, opts Options) error {
+	return r.RenderPDFContext(context.Background(), w, markdown, opts)
+}
+
+// RenderPDFContext implements ContextPDFRenderer. Filters are stopped when
+// ctx is done; typesetting itself runs to completion in the background but
+// its output is discarded.
+func (r *NativeRenderer) RenderPDFContext(ctx context.Context, w io.Writer, markdown string, opts Options) error {
+	doc, err := parseDocument(markdown, opts)
+	if err != nil {
+		return err
+	}
+	if err := applyFilters(ctx, doc, opts, FilterFormatPDF); err != nil {
+		return err
+	}
+	if err := renderDiagrams(ctx, doc, opts); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = runContext(ctx, opts.Timeout, func() error {
+		return renderNative(&buf, doc, opts, nil)
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write PDF output: %w", err)
+	}
+	return nil
+}
+
+// RenderBook implements BookRenderer
+func (r *NativeRenderer) RenderBook(w io.Writer, book *Book, opts Options) error {
+	return r.RenderBookContext(context.Background(), w, book, opts)
+}
+
+// RenderBookContext implements ContextBookRenderer. Like RenderPDFContext
+// it stops filters when ctx is done and discards the output of typesetting
+// that outlives ctx.
+func (r *NativeRenderer) RenderBookContext(ctx context.Context, w io.Writer, book *Book, opts Options) error {
+	doc, err := bookDocument(ctx, book, opts, FilterFormatPDF)
+	if err != nil {
+		return err
+	}
+	opts.Metadata = withTitle(opts.Metadata, book.Title)
+
+	var buf bytes.Buffer
+	err = runContext(ctx, opts.Timeout, func() error {
+		return renderNative(&buf, doc, opts, book)
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write PDF output: %w", err)
+	}
+	return nil
+}
+
+// renderNative typesets a parsed document. A book additionally gets a title
+// page, a table of contents and page numbers in the footer.
+func renderNative(w io.Writer, doc *document, opts Options, book *Book) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(nativeMargin, nativeMargin, nativeMargin)
+	pdf.SetAutoPageBreak(true, nativeMargin)
+
+	np := newNativePDF(pdf, doc.source, opts.Theme)
+	np.keepTogether = keepTogether(doc.meta)
+	wm, err := resolveWatermark(opts.Watermark, doc.meta, opts.Includes)
+	if err != nil {
+		return err
+	}
+	np.watermark = wm
+	np.loadFonts(opts.Fonts)
+	np.prepareAnchors(doc.root)
+	np.prepareFootnotes(doc.root)
+
+	info := resolveMetadata(opts.Metadata, doc)
+	pdf.SetTitle(info.Title, true)
+	pdf.SetAuthor(info.Author, true)
+	pdf.SetSubject(info.Subject, true)
+	pdf.SetKeywords(strings.Join(info.Keywords, ", "), true)
+	pdf.SetCreator(info.Creator, true)
+	pdf.SetCreationDate(info.Created)
+	if info.Language != "" {
+		pdf.SetLang(info.Language)
+	}
+
+	if book != nil {
+		np.pageNumbers()
+		if book.Title != "" {
+			np.renderTitlePage(book.Title)
+		}
+		np.renderTOC(doc.root, book.tocTitle())
+		pdf.AddPage()
+	} else {
+		pdf.AddPage()
+	}
+	np.renderBlocks(doc.root)
+	if len(np.nextNotes) > 0 {
+		// Footnotes that did not fit on the last page
+		pdf.AddPage()
+	}
+
+	// Fill in the page numbers of the table of contents
+	for alias, id := range np.tocAliases {
+		pdf.RegisterAlias(alias, strconv.Itoa(np.headingPages[id]))
+	}
+
+	// Write PDF into memory so nothing reaches w on failure
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return fmt.Errorf("failed to generate PDF: %w", err)
+	}
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write PDF output: %w", err)
+	}
+
+	return nil
+}
+
+// nativeMargin is the page margin of native PDFs in mm
+const nativeMargin = 20
+
+// nativePalette holds the colours used for one theme
+type nativePalette struct {
+	background [3]int
+	text       [3]int
+	muted      [3]int
+	link       [3]int
+	codeFill   [3]int
+	codeText   [3]int
+	border     [3]int
+	accent     [3]int
+}
+
+var nativePalettes = map[string]nativePalette{
+	"light": {
+		background: [3]int{255, 255, 255},
+		text:       [3]int{33, 37, 41},
+		muted:      [3]int{108, 117, 125},
+		link:       [3]int{13, 110, 253},
+		codeFill:   [3]int{248, 249, 250},
+		codeText:   [3]int{33, 37, 41},
+		border:     [3]int{222, 226, 230},
+		accent:     [3]int{0, 123, 255},
+	},
+	"dark": {
+		background: [3]int{33, 37, 41},
+		text:       [3]int{255, 255, 255},
+		muted:      [3]int{233, 236, 239},
+		link:       [3]int{134, 183, 254},
+		codeFill:   [3]int{45, 55, 72},
+		codeText:   [3]int{226, 232, 240},
+		border:     [3]int{73, 80, 87},
+		accent:     [3]int{0, 123, 255},
+	},
+}
+
+// Font roles are registered under these family names when a TrueType font is found
+const (
+	nativeSans    = "docsans"
+	nativeHeading = "docheading"
+	nativeMono    = "docmono"
+)
+
+// nativeFontCandidates lists well-known TrueType fonts with Unicode coverage per role
+var nativeFontCandidates = map[string][]string{
+	"": {
+		"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+		"/usr/share/fonts/TTF/DejaVuSans.ttf",
+		"/usr/share/fonts/dejavu/DejaVuSans.ttf",
+		"C:\\Windows\\Fonts\\arial.ttf",
+		"/System/Library/Fonts/Supplemental/Arial.ttf",
+		"/Library/Fonts/Arial.ttf",
+	},
+	"B": {
+		"/usr/share/fonts/truetype/dejavu/DejaVuSans-Bold.ttf",
+		"/usr/share/fonts/TTF/DejaVuSans-Bold.ttf",
+		"/usr/share/fonts/dejavu/DejaVuSans-Bold.ttf",
+		"C:\\Windows\\Fonts\\arialbd.ttf",
+		"/System/Library/Fonts/Supplemental/Arial Bold.ttf",
+		"/Library/Fonts/Arial Bold.ttf",
+	},
+	"I": {
+		"/usr/share/fonts/truetype/dejavu/DejaVuSans-Oblique.ttf",
+		"/usr/share/fonts/TTF/DejaVuSans-Oblique.ttf",
+		"/usr/share/fonts/dejavu/DejaVuSans-Oblique.ttf",
+		"C:\\Windows\\Fonts\\ariali.ttf",
+		"/System/Library/Fonts/Supplemental/Arial Italic.ttf",
+		"/Library/Fonts/Arial Italic.ttf",
+	},
+	"BI": {
+		"/usr/share/fonts/truetype/dejavu/DejaVuSans-BoldOblique.ttf",
+		"/usr/share/fonts/TTF/DejaVuSans-BoldOblique.ttf",
+		"/usr/share/fonts/dejavu/DejaVuSans-BoldOblique.ttf",
+		"C:\\Windows\\Fonts\\arialbi.ttf",
+		"/System/Library/Fonts/Supplemental/Arial Bold Italic.ttf",
+		"/Library/Fonts/Arial Bold Italic.ttf",
+	},
+	"mono": {
+		"/usr/share/fonts/truetype/dejavu/DejaVuSansMono.ttf",
+		"/usr/share/fonts/TTF/DejaVuSansMono.ttf",
+		"/usr/share/fonts/dejavu/DejaVuSansMono.ttf",
+		"C:\\Windows\\Fonts\\consola.ttf",
+		"C:\\Windows\\Fonts\\cour.ttf",
+		"/System/Library/Fonts/Supplemental/Courier New.ttf",
+		"/Library/Fonts/Courier New.ttf",
+	},
+}
+
+// NativeFonts reports the TrueType font file the native engine uses for each
+// role. Roles without an entry fall back to the PDF core fonts.
+func NativeFonts() map[string]string {
+	roles := map[string]string{"": "regular", "B": "bold", "I": "italic", "BI": "bold italic", "mono": "monospace"}
+	fonts := make(map[string]string)
+	for style, role := range roles {
+		if file := findFontFile(nativeFontCandidates[style]); file != "" {
+			fonts[role] = file
+		}
+	}
+	return fonts
+}
+
+// inlineStyle is the formatting applied to a run of inline text
+type inlineStyle struct {
+	bold    bool
+	italic  bool
+	strike  bool
+	code    bool
+	heading bool
+	link    string
+}
+
+// nativePDF carries the rendering state for one document
+type nativePDF struct {
+	pdf     *fpdf.Fpdf
+	source  []byte
+	palette nativePalette
+	dark    bool
+
+	// sans, heading and mono are the font families in use; translate
+	// converts UTF-8 text for the core fonts when no TrueType font could be
+	// loaded
+	sans      string
+	heading   string
+	mono      string
+	translate func(string) string
+
+	fontSize  float64
+	textColor [3]int
+
+	// watermark is stamped on every page when set
+	watermark *Watermark
+
+	// keepTogether moves tables and code blocks that would be split to the
+	// next page when they fit on one page
+	keepTogether bool
+
+	// anchors maps heading IDs to fpdf link targets so "#id" links work;
+	// headingPages records the page each heading landed on
+	anchors      map[string]int
+	headingPages map[string]int
+
+	// tocAliases maps page number placeholders in the table of contents to
+	// heading IDs; they are resolved when the PDF is written
+	tocAliases map[string]string
+
+	// bookmarkLevel is the outline level of the previous heading
+	bookmarkLevel int
+
+	// footnotes maps footnote indexes to their definitions. pageNotes are
+	// the footnotes referenced on the current page, printed at its bottom
+	// in the notesHeight mm reserved above the margin; nextNotes did not
+	// fit there and go to the next page.
+	footnotes   map[int]*east.Footnote
+	pageNotes   []pageNote
+	nextNotes   []pageNote
+	notesHeight float64
+
+	// numberPages prints page numbers in the footer
+	numberPages bool
+}
+
+func newNativePDF(pdf *fpdf.Fpdf, source []byte, theme string) *nativePDF {
+	palette, ok := nativePalettes[theme]
+	if !ok {
+		palette = nativePalettes["light"]
+	}
+
+	np := &nativePDF{
+		pdf:       pdf,
+		source:    source,
+		palette:   palette,
+		dark:      theme == ThemeDark,
+		sans:      "Helvetica",
+		heading:   "Helvetica",
+		mono:      "Courier",
+		translate: func(s string) string { return s },
+		fontSize:  11,
+		textColor: palette.text,
+
+		anchors:       make(map[string]int),
+		headingPages:  make(map[string]int),
+		tocAliases:    make(map[string]string),
+		bookmarkLevel: -1,
+		footnotes:     make(map[int]*east.Footnote),
+	}
+
+	// Paint the page background for dark output and stamp the watermark
+	pdf.SetHeaderFuncMode(func() {
+		if theme == "dark" {
+			width, height := pdf.GetPageSize()
+			pdf.SetFillColor(palette.background[0], palette.background[1], palette.background[2])
+			pdf.Rect(0, 0, width, height, "F")
+		}
+		if np.watermark != nil {
+			drawWatermark(pdf, np.watermark, palette.text, np.translate, np.sans)
+		}
+	}, false)
+	pdf.SetFooterFunc(np.footer)
+
+	return np
+}
+
+// loadFonts registers the configured TrueType fonts, then well-known system
+// fonts, and falls back to the built-in core fonts when neither exists
+func (np *nativePDF) loadFonts(cfg *FontConfig) {
+	var faces []FontFace
+	if cfg != nil {
+		var err error
+		if faces, err = cfg.Faces(); err != nil {
+			np.pdf.SetError(err)
+			return
+		}
+	} else {
+		cfg = &FontConfig{}
+	}
+
+	system := map[string]string{}
+	for _, style := range []string{"", "B", "I", "BI"} {
+		system[style] = findFontFile(nativeFontCandidates[style])
+	}
+
+	// Body text decides between TrueType and core fonts for the whole document
+	if !np.registerFamily(nativeSans, faceFiles(faces, cfg.Body)) &&
+		!np.registerFamily(nativeSans, system) {
+		np.translate = np.pdf.UnicodeTranslatorFromDescriptor("")
+		return
+	}
+	np.sans = nativeSans
+	np.translate = basicPlane
+
+	np.heading = np.sans
+	if cfg.Headings != "" && np.registerFamily(nativeHeading, faceFiles(faces, cfg.Headings)) {
+		np.heading = nativeHeading
+	}
+
+	mono := map[string]string{"": findFontFile(nativeFontCandidates["mono"])}
+	if np.registerFamily(nativeMono, faceFiles(faces, cfg.Code)) || np.registerFamily(nativeMono, mono) {
+		np.mono = nativeMono
+	}
+}
+
+// basicPlane drops characters outside the Basic Multilingual Plane, such
+// as most emoji, which fpdf cannot map in TrueType fonts
+func basicPlane(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > 0xFFFF {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// registerFamily adds a font family from style ("", "B", "I", "BI") to file
+// mappings. Missing styles reuse the closest available file. It reports
+// false when there is no regular face.
+func (np *nativePDF) registerFamily(family string, files map[string]string) bool {
+	regular := files[""]
+	if regular == "" {
+		return false
+	}
+
+	for _, style := range []string{"", "B", "I", "BI"} {
+		file := files[style]
+		if file == "" && style == "BI" {
+			file = files["B"]
+		}
+		if file == "" {
+			file = regular
+		}
+		np.addFont(family, style, file)
+	}
+	return true
+}
+
+// faceFiles picks TrueType files of family for each fpdf style
+func faceFiles(faces []FontFace, family string) map[string]string {
+	files := map[string]string{}
+	best := map[string]int{}
+	for _, face := range faces {
+		if face.Family != family || face.Format != "truetype" {
+			continue
+		}
+
+		style, target := "", 400
+		if face.Weight >= 600 {
+			style, target = "B", 700
+		}
+		if face.Italic {
+			style += "I"
+		}
+
+		distance := face.Weight - target
+		if distance < 0 {
+			distance = -distance
+		}
+		if current, ok := best[style]; !ok || distance < current {
+			best[style] = distance
+			files[style] = face.Path
+		}
+	}
+	return files
+}
+
+func (np *nativePDF) addFont(family, style, file string) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		np.pdf.SetError(fmt.Errorf("failed to read font %s: %w", file, err))
+		return
+	}
+	np.pdf.AddUTF8FontFromBytes(family, style, data)
+}
+
+// findFontFile returns the first existing file from candidates
+func findFontFile(candidates []string) string {
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// lineHeight returns the line height in mm for a font size in points
+func lineHeight(size float64) float64 {
+	return size * 0.3528 * 1.5
+}
+
+func (np *nativePDF) setColor(c [3]int) {
+	np.pdf.SetTextColor(c[0], c[1], c[2])
+}
+
+func (np *nativePDF) setFont(st inlineStyle, size float64) {
+	family := np.sans
+	if st.code {
+		family = np.mono
+	} else if st.heading {
+		family = np.heading
+	}
+
+	style := ""
+	if st.bold {
+		style += "B"
+	}
+	if st.italic {
+		style += "I"
+	}
+	if st.strike {
+		style += "S"
+	}
+	if st.link != "" {
+		style += "U"
+	}
+
+	np.pdf.SetFont(family, style, size)
+}
+
+// newLine moves to the start of the next line if the cursor is mid-line
+func (np *nativePDF) newLine() {
+	left, _, _, _ := np.pdf.GetMargins()
+	if np.pdf.GetX() > left+0.01 {
+		np.pdf.Ln(lineHeight(np.fontSize))
+	}
+}
+
+// contentWidth returns the width between the current margins
+func (np *nativePDF) contentWidth() float64 {
+	left, _, right, _ := np.pdf.GetMargins()
+	width, _ := np.pdf.GetPageSize()
+	return width - left - right
+}
+
+// atPageTop reports whether nothing has been written on the current page yet
+func (np *nativePDF) atPageTop() bool {
+	_, top, _, _ := np.pdf.GetMargins()
+	return np.pdf.GetY() <= top+0.01
+}
+
+// newPage starts a new page unless the current one is still empty
+func (np *nativePDF) newPage() {
+	np.newLine()
+	if !np.atPageTop() {
+		np.pdf.AddPage()
+	}
+}
+
+// keep starts a new page when a block of the given height would be split
+// but fits on a page of its own
+func (np *nativePDF) keep(height float64) {
+	if !np.keepTogether {
+		return
+	}
+	_, top, _, bottom := np.pdf.GetMargins()
+	_, pageHeight := np.pdf.GetPageSize()
+	if np.pdf.GetY()+height > pageHeight-bottom && height <= pageHeight-top-bottom {
+		np.newPage()
+	}
+}
+
+// withIndent renders fn with the left margin moved right by indent mm
+func (np *nativePDF) withIndent(indent float64, fn func()) {
+	left, _, _, _ := np.pdf.GetMargins()
+	np.pdf.SetLeftMargin(left + indent)
+	fn()
+	np.pdf.SetLeftMargin(left)
+	if np.pdf.GetX() < left {
+		np.pdf.SetX(left)
+	}
+}
+
+func (np *nativePDF) renderBlocks(parent ast.Node) {
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		np.renderBlock(n)
+	}
+}
+
+func (np *nativePDF) renderBlock(n ast.Node) {
+	pdf := np.pdf
+
+	switch n := n.(type) {
+	case *ast.Heading:
+		np.renderHeading(n)
+
+	case *ast.Paragraph:
+		np.renderInlines(n, inlineStyle{})
+		np.newLine()
+		pdf.Ln(2)
+
+	case *ast.TextBlock:
+		np.renderInlines(n, inlineStyle{})
+		np.newLine()
+
+	case *ast.List:
+		np.renderList(n)
+
+	case *ast.Blockquote:
+		np.renderBlockquote(n)
+
+	case *Admonition:
+		np.renderAdmonition(n)
+
+	case *ast.FencedCodeBlock:
+		np.renderCode(n)
+
+	case *ast.CodeBlock:
+		np.renderCode(n)
+
+	case *ast.ThematicBreak:
+		np.newLine()
+		left, _, right, _ := pdf.GetMargins()
+		width, _ := pdf.GetPageSize()
+		y := pdf.GetY() + 3
+		pdf.SetDrawColor(np.palette.border[0], np.palette.border[1], np.palette.border[2])
+		pdf.SetLineWidth(0.3)
+		pdf.Line(left, y, width-right, y)
+		pdf.SetY(y + 4)
+
+	case *east.Table:
+		np.renderTable(n)
+
+	case *PageBreak:
+		np.newPage()
+
+	case *ast.HTMLBlock:
+		// Raw HTML has no meaning in the native renderer
+
+	case *east.FootnoteList:
+		// Footnotes are printed at the bottom of the pages citing them
+
+	case *east.DefinitionTerm:
+		np.renderInlines(n, inlineStyle{bold: true})
+		np.newLine()
+
+	case *east.DefinitionDescription:
+		np.withIndent(6, func() {
+			np.renderBlocks(n)
+		})
+		np.newLine()
+		pdf.Ln(1)
+
+	case *Diagram:
+		if !np.renderSVG(n.SVG, n.Language != chartLanguage) {
+			np.renderCode(n)
+		}
+
+	case *MathBlock:
+		// Math is set as linear text
+		tex, _ := mathTeX(n, np.source)
+		np.newLine()
+		np.setFont(inlineStyle{}, np.fontSize)
+		np.setColor(np.textColor)
+		pdf.MultiCell(0, lineHeight(np.fontSize), np.translate(texToText(tex)), "", "C", false)
+		pdf.Ln(2)
+
+	default:
+		// Blocks added by extensions keep at least their text
+		if child := n.FirstChild(); child != nil && child.Type() == ast.TypeInline {
+			np.renderInlines(n, inlineStyle{})
+			np.newLine()
+			pdf.Ln(2)
+		} else {
+			np.renderBlocks(n)
+		}
+	}
+}
+
+var headingSizes = map[int]float64{1: 22, 2: 18, 3: 15, 4: 13, 5: 12, 6: 11}
+
+func (np *nativePDF) renderHeading(n *ast.Heading) {
+	pdf := np.pdf
+	np.newLine()
+	if hasClass(n, classBreakBefore) {
+		np.newPage()
+	}
+
+	// Leave space above headings except at the top of a page
+	if !np.atPageTop() {
+		pdf.Ln(4)
+	}
+
+	np.markHeading(n)
+
+	size := headingSizes[n.Level]
+	saved := np.fontSize
+	np.fontSize = size
+	np.renderInlines(n, inlineStyle{bold: true, heading: true})
+	np.newLine()
+	np.fontSize = saved
+
+	// Underline the top two levels like the HTML wrapper does
+	if n.Level <= 2 {
+		left, _, right, _ := pdf.GetMargins()
+		width, _ := pdf.GetPageSize()
+		y := pdf.GetY() + 1
+		pdf.SetDrawColor(np.palette.border[0], np.palette.border[1], np.palette.border[2])
+		pdf.SetLineWidth(0.4)
+		pdf.Line(left, y, width-right, y)
+		pdf.SetY(y + 2)
+	}
+	pdf.Ln(2)
+}
+
+// prepareAnchors creates a link target for every heading with an ID so
+// links can point at headings that have not been rendered yet
+func (np *nativePDF) prepareAnchors(root ast.Node) {
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if heading, ok := n.(*ast.Heading); ok && entering {
+			if id, ok := headingID(heading); ok && id != "" {
+				np.anchors[id] = np.pdf.AddLink()
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// markHeading points the heading's link target at the cursor and adds it
+// to the document outline
+func (np *nativePDF) markHeading(n *ast.Heading) {
+	pdf := np.pdf
+	if id, ok := headingID(n); ok {
+		if link, ok := np.anchors[id]; ok {
+			pdf.SetLink(link, pdf.GetY(), -1)
+			np.headingPages[id] = pdf.PageNo()
+		}
+	}
+
+	// fpdf encodes outline titles for the current font, which must be the
+	// document's own
+	np.setFont(inlineStyle{bold: true, heading: true}, headingSizes[n.Level])
+
+	// fpdf requires outline levels to grow one step at a time
+	level := n.Level - 1
+	if level > np.bookmarkLevel+1 {
+		level = np.bookmarkLevel + 1
+	}
+	np.bookmarkLevel = level
+	pdf.Bookmark(np.translate(np.plainText(n)), level, -1)
+}
+
+// pageNumbers prints the page number at the bottom of every page
+func (np *nativePDF) pageNumbers() {
+	np.numberPages = true
+}
+
+// footer finishes a page with its footnotes and page number
+func (np *nativePDF) footer() {
+	pdf := np.pdf
+	np.printFootnotes()
+	if np.numberPages {
+		pdf.SetY(-12)
+		pdf.SetFont(np.sans, "", 9)
+		np.setColor(np.palette.muted)
+		pdf.CellFormat(0, 5, strconv.Itoa(pdf.PageNo()), "", 0, "C", false, 0, "")
+	}
+}
+
+// footnoteSize is the font size of footnotes
+const footnoteSize = 8.0
+
+// pageNote is a footnote waiting for the bottom of a page
+type pageNote struct {
+	text   string
+	height float64
+}
+
+// prepareFootnotes collects the footnote definitions, which are printed at
+// the bottom of the page that first references them instead of at the end
+func (np *nativePDF) prepareFootnotes(root ast.Node) {
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if note, ok := n.(*east.Footnote); ok && entering {
+			np.footnotes[note.Index] = note
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// footnoteText is the line printed for a footnote
+func (np *nativePDF) footnoteText(note *east.Footnote) string {
+	return np.translate(fmt.Sprintf("%d. %s", note.Index, strings.TrimSpace(np.plainText(note))))
+}
+
+// footnoteRef prints a raised footnote number and reserves room for the
+// note at the bottom of the page
+func (np *nativePDF) footnoteRef(index int, st inlineStyle) {
+	pdf := np.pdf
+	np.setFont(st, np.fontSize)
+	np.setColor(np.palette.link)
+	pdf.SubWrite(lineHeight(np.fontSize), strconv.Itoa(index), np.fontSize*0.65, 4, 0, "")
+	np.setColor(np.textColor)
+
+	note, ok := np.footnotes[index]
+	if !ok {
+		return
+	}
+	delete(np.footnotes, index)
+
+	text := np.footnoteText(note)
+	pdf.SetFont(np.sans, "", footnoteSize)
+	_, _, right, _ := pdf.GetMargins()
+	width, height := pdf.GetPageSize()
+	lines := pdf.SplitText(text, width-nativeMargin-right)
+	np.setFont(st, np.fontSize)
+
+	pending := pageNote{text: text, height: float64(len(lines)) * lineHeight(footnoteSize)}
+	if len(np.nextNotes) > 0 || pdf.GetY()+lineHeight(np.fontSize) > height-nativeMargin-np.notesHeight-pending.height-3 {
+		np.nextNotes = append(np.nextNotes, pending)
+		return
+	}
+	np.addPageNote(pending)
+}
+
+// addPageNote reserves room for a note at the bottom of the current page
+func (np *nativePDF) addPageNote(note pageNote) {
+	if len(np.pageNotes) == 0 {
+		// Room for the separator rule
+		np.notesHeight += 3
+	}
+	np.pageNotes = append(np.pageNotes, note)
+	np.notesHeight += note.height
+	np.pdf.SetAutoPageBreak(true, nativeMargin+np.notesHeight)
+}
+
+// printFootnotes prints the notes referenced on the current page above its
+// bottom margin
+func (np *nativePDF) printFootnotes() {
+	pdf := np.pdf
+	if len(np.pageNotes) > 0 {
+		left, _, right, _ := pdf.GetMargins()
+		width, height := pdf.GetPageSize()
+		y := height - nativeMargin - np.notesHeight
+		pdf.SetDrawColor(np.palette.border[0], np.palette.border[1], np.palette.border[2])
+		pdf.SetLineWidth(0.3)
+		pdf.Line(nativeMargin, y+1, nativeMargin+50, y+1)
+
+		pdf.SetLeftMargin(nativeMargin)
+		pdf.SetFont(np.sans, "", footnoteSize)
+		np.setColor(np.palette.muted)
+		pdf.SetY(y + 3)
+		for _, note := range np.pageNotes {
+			pdf.SetX(nativeMargin)
+			pdf.MultiCell(width-nativeMargin-right, lineHeight(footnoteSize), note.text, "", "L", false)
+		}
+		pdf.SetLeftMargin(left)
+	}
+
+	np.pageNotes = nil
+	np.notesHeight = 0
+	pdf.SetAutoPageBreak(true, nativeMargin)
+
+	next := np.nextNotes
+	np.nextNotes = nil
+	for _, note := range next {
+		np.addPageNote(note)
+	}
+}
+
+// renderTitlePage prints the book title centred on a page of its own
+func (np *nativePDF) renderTitlePage(title string) {
+	pdf := np.pdf
+	pdf.AddPage()
+	_, pageHeight := pdf.GetPageSize()
+	pdf.SetY(pageHeight * 0.35)
+	pdf.SetFont(np.heading, "B", 28)
+	np.setColor(np.textColor)
+	pdf.MultiCell(0, lineHeight(28), np.translate(title), "", "C", false)
+}
+
+// renderTOC lists the level 1 and 2 headings with links and page numbers.
+// Page numbers are placeholders filled in once the body has been laid out;
+// they are printed with a core font, which always has glyphs for digits.
+func (np *nativePDF) renderTOC(root ast.Node, title string) {
+	pdf := np.pdf
+	pdf.AddPage()
+
+	pdf.SetFont(np.heading, "B", headingSizes[1])
+	np.setColor(np.textColor)
+	pdf.Write(lineHeight(headingSizes[1]), np.translate(title))
+	pdf.Ln(lineHeight(headingSizes[1]) + 4)
+
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	numberWidth := 12.0
+	lh := lineHeight(np.fontSize)
+
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering || heading.Level > 2 {
+			return ast.WalkContinue, nil
+		}
+		id, _ := headingID(heading)
+		link, ok := np.anchors[id]
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		indent := float64(heading.Level-1) * 6
+		style := ""
+		if heading.Level == 1 {
+			style = "B"
+		}
+		pdf.SetFont(np.sans, style, np.fontSize)
+		np.setColor(np.textColor)
+
+		// Shorten entries that would run into the page number column
+		width := pageWidth - left - right - indent - numberWidth
+		text := np.translate(np.plainText(heading))
+		for text != "" && pdf.GetStringWidth(text) > width {
+			runes := []rune(text)
+			text = string(runes[:len(runes)-1])
+		}
+
+		y := pdf.GetY()
+		pdf.SetX(left + indent)
+		pdf.CellFormat(width, lh, text, "", 0, "L", false, link, "")
+
+		alias := fmt.Sprintf("{toc%d}", len(np.tocAliases))
+		np.tocAliases[alias] = id
+		pdf.SetFont("Helvetica", "", np.fontSize)
+		pdf.SetXY(pageWidth-right-numberWidth, y)
+		pdf.CellFormat(numberWidth, lh, alias, "", 1, "L", false, link, "")
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+func (np *nativePDF) renderList(n *ast.List) {
+	pdf := np.pdf
+	np.newLine()
+
+	number := n.Start
+	for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+		marker := "•"
+		if np.sans != nativeSans {
+			marker = "-"
+		}
+		if n.IsOrdered() {
+			marker = fmt.Sprintf("%d%c", number, n.Marker)
+			number++
+		}
+
+		// Draw the marker in the gutter, then indent the item body
+		np.setFont(inlineStyle{}, np.fontSize)
+		np.setColor(np.textColor)
+		pdf.CellFormat(7, lineHeight(np.fontSize), np.translate(marker), "", 0, "L", false, 0, "")
+		np.withIndent(7, func() {
+			np.renderBlocks(item)
+		})
+		np.newLine()
+	}
+
+	if n.IsTight {
+		pdf.Ln(2)
+	}
+}
+
+func (np *nativePDF) renderBlockquote(n *ast.Blockquote) {
+	pdf := np.pdf
+	np.newLine()
+
+	startPage := pdf.PageNo()
+	startY := pdf.GetY()
+	left, top, _, _ := pdf.GetMargins()
+
+	saved := np.textColor
+	np.textColor = np.palette.muted
+	np.withIndent(6, func() {
+		np.renderBlocks(n)
+	})
+	np.textColor = saved
+
+	// Draw the accent bar on the final page of the quote
+	if pdf.PageNo() != startPage {
+		startY = top
+	}
+	pdf.SetDrawColor(np.palette.accent[0], np.palette.accent[1], np.palette.accent[2])
+	pdf.SetLineWidth(1)
+	pdf.Line(left+1, startY, left+1, pdf.GetY()-2)
+}
+
+// renderAdmonition sets the title in the accent colour of the admonition
+// and marks the body with a bar like a quote
+func (np *nativePDF) renderAdmonition(n *Admonition) {
+	pdf := np.pdf
+	np.newLine()
+
+	title, style := admonitionTitle(n)
+	accent := style.light
+	if np.dark {
+		accent = style.dark
+	}
+
+	startPage := pdf.PageNo()
+	startY := pdf.GetY()
+	left, top, _, _ := pdf.GetMargins()
+
+	np.withIndent(6, func() {
+		np.keep(3 * lineHeight(np.fontSize))
+		if pdf.PageNo() != startPage {
+			startPage = pdf.PageNo()
+			startY = pdf.GetY()
+		}
+		saved := np.textColor
+		np.textColor = accent
+		np.write(title, inlineStyle{bold: true})
+		np.textColor = saved
+		np.newLine()
+		pdf.Ln(1)
+		np.renderBlocks(n)
+	})
+
+	// Draw the accent bar on the final page of the admonition
+	if pdf.PageNo() != startPage {
+		startY = top
+	}
+	pdf.SetDrawColor(accent[0], accent[1], accent[2])
+	pdf.SetLineWidth(1)
+	pdf.Line(left+1, startY, left+1, pdf.GetY()-2)
+	pdf.Ln(2)
+}
+
+func (np *nativePDF) renderCode(n ast.Node) {
+	pdf := np.pdf
+	np.newLine()
+
+	var code strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(np.source))
+	}
+	content := strings.TrimRight(strings.ReplaceAll(code.String(), "\t", "    "), "\n")
+
+	// Titles and line numbers carry over from the info string; emphasized
+	// lines and copy buttons only make sense on screen
+	var info codeInfo
+	if fenced, ok := n.(*ast.FencedCodeBlock); ok {
+		info = parseCodeInfo(fenced, np.source, false)
+	}
+	if info.lineNumbers {
+		numbered := strings.Split(content, "\n")
+		width := len(strconv.Itoa(len(numbered)))
+		for i, line := range numbered {
+			number := strconv.Itoa(i + 1)
+			numbered[i] = strings.Repeat(" ", width-len(number)) + number + "  " + line
+		}
+		content = strings.Join(numbered, "\n")
+	}
+
+	size := 9.0
+	titleHeight := 0.0
+	if info.title != "" {
+		titleHeight = lineHeight(size-1) + 1
+	}
+	np.setFont(inlineStyle{code: true}, size)
+	np.keep(float64(len(pdf.SplitText(np.translate(content), np.contentWidth()-6)))*lineHeight(size) + titleHeight)
+	if info.title != "" {
+		np.setFont(inlineStyle{code: true, bold: true}, size-1)
+		np.setColor(np.palette.muted)
+		pdf.MultiCell(0, lineHeight(size-1), np.translate(info.title), "", "L", false)
+		pdf.Ln(1)
+		np.setFont(inlineStyle{code: true}, size)
+	}
+	pdf.SetFillColor(np.palette.codeFill[0], np.palette.codeFill[1], np.palette.codeFill[2])
+	pdf.SetTextColor(np.palette.codeText[0], np.palette.codeText[1], np.palette.codeText[2])
+	pdf.SetCellMargin(3)
+	pdf.MultiCell(0, lineHeight(size), np.translate(content), "", "L", true)
+	pdf.SetCellMargin(1)
+	pdf.Ln(3)
+}
+
+// renderSVG draws an svg element centred on the page, scaled down to fit,
+// on a white backdrop in dark output if backdrop is set; it reports false
+// when the SVG cannot be read
+func (np *nativePDF) renderSVG(data []byte, backdrop bool) bool {
+	root, err := parseSVG(data)
+	if err != nil {
+		return false
+	}
+	pdf := np.pdf
+
+	width, height, m := svgSize(root)
+	_, top, _, bottom := pdf.GetMargins()
+	_, pageHeight := pdf.GetPageSize()
+	fit := func(available, size float64) {
+		if size > available {
+			scale := available / size
+			width, height = width*scale, height*scale
+			m = svgMatrix{scale, 0, 0, scale, 0, 0}.then(m)
+		}
+	}
+	fit(np.contentWidth(), width)
+	fit(pageHeight-top-bottom-4, height)
+
+	np.newLine()
+	if pdf.GetY()+height+4 > pageHeight-bottom {
+		pdf.AddPage()
+	}
+	left, _, _, _ := pdf.GetMargins()
+	x := left + (np.contentWidth()-width)/2
+	y := pdf.GetY() + 2
+
+	// Diagram tools draw black on white, so keep a light backdrop
+	if np.dark && backdrop {
+		pdf.SetFillColor(255, 255, 255)
+		pdf.RoundedRect(x-2, y-2, width+4, height+4, 1.5, "1234", "F")
+	}
+
+	d := &svgDrawer{pdf: pdf, sans: np.sans, mono: np.mono, translate: np.translate}
+	d.draw(root, svgDefaultStyle, svgMatrix{1, 0, 0, 1, x, y}.then(m), 1)
+
+	pdf.SetLineWidth(0.2)
+	np.setFont(inlineStyle{}, np.fontSize)
+	np.setColor(np.textColor)
+	pdf.SetXY(left, y+height+4)
+	return true
+}
+
+func (np *nativePDF) renderTable(n *east.Table) {
+	pdf := np.pdf
+	np.newLine()
+
+	// Collect plain cell text; inline styling is not kept inside tables
+	var rows [][]string
+	header := 0
+	for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells []string
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, np.translate(np.plainText(cell)))
+		}
+		if _, ok := row.(*east.TableHeader); ok {
+			header = 1
+		}
+		rows = append(rows, cells)
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	size := 10.0
+	lh := lineHeight(size)
+	padding := 2.0
+
+	// Size columns by their widest cell, scaled to the available width
+	columns := len(n.Alignments)
+	natural := make([]float64, columns)
+	total := 0.0
+	for r, cells := range rows {
+		np.setFont(inlineStyle{bold: r < header}, size)
+		for c := 0; c < columns && c < len(cells); c++ {
+			if w := pdf.GetStringWidth(cells[c]) + 2*padding; w > natural[c] {
+				natural[c] = w
+			}
+		}
+	}
+	for c := range natural {
+		if natural[c] < 10 {
+			natural[c] = 10
+		}
+		total += natural[c]
+	}
+
+	left, _, right, bottom := pdf.GetMargins()
+	pageWidth, pageHeight := pdf.GetPageSize()
+	available := pageWidth - left - right
+	widths := make([]float64, columns)
+	for c := range natural {
+		widths[c] = natural[c]
+		if total > available {
+			widths[c] = natural[c] * available / total
+		}
+	}
+
+	pdf.SetDrawColor(np.palette.border[0], np.palette.border[1], np.palette.border[2])
+	pdf.SetLineWidth(0.2)
+	pdf.SetFillColor(np.palette.codeFill[0], np.palette.codeFill[1], np.palette.codeFill[2])
+	np.setColor(np.textColor)
+
+	// Wrap every cell and use the tallest one as the row height
+	wrapped := make([][][]string, len(rows))
+	heights := make([]float64, len(rows))
+	tableHeight := 0.0
+	for r, cells := range rows {
+		np.setFont(inlineStyle{bold: r < header}, size)
+		wrapped[r] = make([][]string, columns)
+		lines := 1
+		for c := 0; c < columns; c++ {
+			cell := ""
+			if c < len(cells) {
+				cell = cells[c]
+			}
+			wrapped[r][c] = pdf.SplitText(cell, widths[c]-2*padding)
+			if len(wrapped[r][c]) > lines {
+				lines = len(wrapped[r][c])
+			}
+		}
+		heights[r] = float64(lines)*lh + 2*padding
+		tableHeight += heights[r]
+	}
+	np.keep(tableHeight)
+
+	for r := range rows {
+		np.setFont(inlineStyle{bold: r < header}, size)
+		height := heights[r]
+
+		if pdf.GetY()+height > pageHeight-bottom {
+			pdf.AddPage()
+		}
+
+		x := left
+		y := pdf.GetY()
+		for c := 0; c < columns; c++ {
+			style := "D"
+			if r < header {
+				style = "FD"
+			}
+			pdf.Rect(x, y, widths[c], height, style)
+
+			align := "L"
+			switch n.Alignments[c] {
+			case east.AlignCenter:
+				align = "C"
+			case east.AlignRight:
+				align = "R"
+			}
+			for i, line := range wrapped[r][c] {
+				pdf.SetXY(x+padding, y+padding+float64(i)*lh)
+				pdf.CellFormat(widths[c]-2*padding, lh, line, "", 0, align, false, 0, "")
+			}
+			x += widths[c]
+		}
+		pdf.SetXY(left, y+height)
+	}
+	pdf.Ln(3)
+}
+
+// plainText collects the text of n and its descendants
+func (np *nativePDF) plainText(n ast.Node) string {
+	var sb strings.Builder
+	_ = ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch child := child.(type) {
+		case *ast.Text:
+			sb.Write(child.Segment.Value(np.source))
+			if child.SoftLineBreak() || child.HardLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			if child.IsCode() {
+				sb.WriteString(html.UnescapeString(string(child.Value)))
+			} else {
+				sb.Write(child.Value)
+			}
+		case *ast.AutoLink:
+			sb.Write(child.Label(np.source))
+		}
+		return ast.WalkContinue, nil
+	})
+	return sb.String()
+}
+
+func (np *nativePDF) renderInlines(parent ast.Node, st inlineStyle) {
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		np.renderInline(n, st)
+	}
+}
+
+func (np *nativePDF) renderInline(n ast.Node, st inlineStyle) {
+	switch n := n.(type) {
+	case *ast.Text:
+		np.write(string(n.Segment.Value(np.source)), st)
+		if n.HardLineBreak() {
+			np.pdf.Ln(lineHeight(np.fontSize))
+		} else if n.SoftLineBreak() {
+			np.write(" ", st)
+		}
+
+	case *ast.String:
+		if n.IsCode() {
+			// Typographer output such as &ldquo; is HTML
+			np.write(html.UnescapeString(string(n.Value)), st)
+		} else {
+			np.write(string(n.Value), st)
+		}
+
+	case *ast.CodeSpan:
+		st.code = true
+		np.renderInlines(n, st)
+
+	case *ast.Emphasis:
+		if n.Level >= 2 {
+			st.bold = true
+		} else {
+			st.italic = true
+		}
+		np.renderInlines(n, st)
+
+	case *east.Strikethrough:
+		st.strike = true
+		np.renderInlines(n, st)
+
+	case *ast.Link:
+		st.link = string(n.Destination)
+		np.renderInlines(n, st)
+
+	case *ast.AutoLink:
+		st.link = string(n.URL(np.source))
+		np.write(string(n.Label(np.source)), st)
+
+	case *ast.Image:
+		np.renderImage(n)
+
+	case *east.TaskCheckBox:
+		box := "[ ] "
+		if n.IsChecked {
+			box = "[x] "
+		}
+		np.write(box, st)
+
+	case *ast.RawHTML:
+		// Inline HTML is dropped
+
+	case *east.FootnoteLink:
+		np.footnoteRef(n.Index, st)
+
+	case *MathInline:
+		tex, _ := mathTeX(n, np.source)
+		np.write(texToText(tex), st)
+
+	default:
+		np.renderInlines(n, st)
+	}
+}
+
+// write prints a run of text at the cursor, wrapping at the right margin
+func (np *nativePDF) write(s string, st inlineStyle) {
+	if s == "" {
+		return
+	}
+
+	np.setFont(st, np.fontSize)
+	if strings.HasPrefix(st.link, "#") {
+		np.setColor(np.palette.link)
+		if link, ok := np.anchors[strings.TrimPrefix(st.link, "#")]; ok {
+			np.pdf.WriteLinkID(lineHeight(np.fontSize), np.translate(s), link)
+		} else {
+			np.pdf.Write(lineHeight(np.fontSize), np.translate(s))
+		}
+		np.setColor(np.textColor)
+		return
+	}
+	if st.link != "" {
+		np.setColor(np.palette.link)
+		np.pdf.WriteLinkString(lineHeight(np.fontSize), np.translate(s), st.link)
+		np.setColor(np.textColor)
+		return
+	}
+
+	np.setColor(np.textColor)
+	np.pdf.Write(lineHeight(np.fontSize), np.translate(s))
+}
+
+// renderImage embeds local PNG, JPEG and GIF images; anything else is
+// replaced by its alt text
+func (np *nativePDF) renderImage(n *ast.Image) {
+	pdf := np.pdf
+	dest := string(n.Destination)
+	alt := np.plainText(n)
+
+	imageType := ""
+	if !strings.Contains(dest, "://") {
+		imageType = localImageType(dest)
+	}
+	if imageType == "" {
+		np.write("["+alt+"]", inlineStyle{italic: true, link: linkOrEmpty(dest)})
+		return
+	}
+
+	options := fpdf.ImageOptions{ImageType: imageType, ReadDpi: true}
+	info := pdf.RegisterImageOptions(dest, options)
+	if info == nil {
+		return
+	}
+
+	// Scale down to the content width, keeping the aspect ratio
+	left, _, right, bottom := pdf.GetMargins()
+	pageWidth, pageHeight := pdf.GetPageSize()
+	width, height := info.Extent()
+	if available := pageWidth - left - right; width > available {
+		height = height * available / width
+		width = available
+	}
+
+	np.newLine()
+	if pdf.GetY()+height > pageHeight-bottom {
+		pdf.AddPage()
+	}
+	y := pdf.GetY()
+	pdf.ImageOptions(dest, left, y, width, height, false, options, 0, "")
+	pdf.SetY(y + height + 2)
+}
+
+// localImageType returns the fpdf image type of a local image file, or an
+// empty string if the file cannot be embedded
+func localImageType(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	_, format, err := image.DecodeConfig(file)
+	if err != nil {
+		return ""
+	}
+	switch format {
+	case "png", "gif":
+		return format
+	case "jpeg":
+		return "jpg"
+	}
+	return ""
+}
+
+func linkOrEmpty(dest string) string {
+	if strings.Contains(dest, "://") {
+		return dest
+	}
+	return ""
+}
@@ -0,0 +1,187 @@
+package converter
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// References turns issue numbers (#123), mentions (@alice) and commit SHAs
+// into links. Each template is a URL with {id} in place of the number,
+// user name or full SHA; references without a template stay text.
+type References struct {
+	// GitHub is an owner/repo name that fills the unset templates with
+	// github.com URLs
+	GitHub string `yaml:"github"`
+
+	Issue  string `yaml:"issue"`  // e.g. https://tracker.example.com/browse/APP-{id}
+	User   string `yaml:"user"`   // e.g. https://github.com/{id}
+	Commit string `yaml:"commit"` // e.g. https://git.example.com/app/commit/{id}
+}
+
+// templates returns the issue, user and commit templates with GitHub
+// defaults filled in
+func (r *References) templates() (issue, user, commit string) {
+	issue, user, commit = r.Issue, r.User, r.Commit
+	if repo := strings.Trim(r.GitHub, "/ "); repo != "" {
+		if issue == "" {
+			issue = "https://github.com/" + repo + "/issues/{id}"
+		}
+		if user == "" {
+			user = "https://github.com/{id}"
+		}
+		if commit == "" {
+			commit = "https://github.com/" + repo + "/commit/{id}"
+		}
+	}
+	return issue, user, commit
+}
+
+// referencePattern matches the candidates; boundaries are checked by hand
+// since Go regexps have no lookbehind
+var referencePattern = regexp.MustCompile(`#([0-9]+)|@([A-Za-z0-9](?:[A-Za-z0-9-]{0,38}))|([0-9a-fA-F]{7,40})`)
+
+// Classes of reference links, matching GitHub's
+const (
+	classIssueLink   = "issue-link"
+	classUserMention = "user-mention"
+	classCommitLink  = "commit-link"
+)
+
+// referenceLinks links references in text. Code spans, links, raw HTML
+// and code blocks are left alone.
+type referenceLinks struct {
+	issue, user, commit string
+}
+
+// Transform implements parser.ASTTransformer
+func (t *referenceLinks) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var texts []*ast.Text
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.CodeSpan, *ast.Link, *ast.AutoLink, *ast.Image, *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			texts = append(texts, n)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, n := range texts {
+		t.linkText(n, source)
+	}
+}
+
+// linkText splits a text node around the references it contains
+func (t *referenceLinks) linkText(n *ast.Text, source []byte) {
+	segment := n.Segment
+	value := segment.Value(source)
+	parent := n.Parent()
+
+	start := 0 // Start of the text not yet emitted
+	var replaced bool
+	for _, m := range referencePattern.FindAllSubmatchIndex(value, -1) {
+		if m[0] < start || !referenceBoundary(value, m[0], m[1]) {
+			continue
+		}
+
+		var link *ast.Link
+		label := text.NewSegment(segment.Start+m[0], segment.Start+m[1])
+		switch {
+		case m[2] >= 0 && t.issue != "":
+			link = referenceLink(t.issue, string(value[m[2]:m[3]]), classIssueLink)
+		case m[4] >= 0 && t.user != "":
+			link = referenceLink(t.user, string(value[m[4]:m[5]]), classUserMention)
+		case m[6] >= 0 && t.commit != "" && isCommitSHA(value[m[6]:m[7]]):
+			sha := strings.ToLower(string(value[m[6]:m[7]]))
+			link = referenceLink(t.commit, sha, classCommitLink)
+			// Shown abbreviated like on GitHub
+			label = text.NewSegment(label.Start, label.Start+7)
+		}
+		if link == nil {
+			continue
+		}
+
+		if m[0] > start {
+			parent.InsertBefore(parent, n, ast.NewTextSegment(text.NewSegment(segment.Start+start, segment.Start+m[0])))
+		}
+		link.AppendChild(link, ast.NewTextSegment(label))
+		parent.InsertBefore(parent, n, link)
+		start = m[1]
+		replaced = true
+	}
+
+	if replaced {
+		// The original node keeps the rest of the text and its line break
+		n.Segment = text.NewSegment(segment.Start+start, segment.Stop)
+		if n.Segment.IsEmpty() && !n.SoftLineBreak() && !n.HardLineBreak() {
+			parent.RemoveChild(parent, n)
+		}
+	}
+}
+
+// referenceBoundary reports whether value[start:end] stands apart from
+// surrounding words, so e-mail addresses, entities such as &#123; and
+// hex runs inside longer words are not linked
+func referenceBoundary(value []byte, start, end int) bool {
+	if start > 0 {
+		prev := value[start-1]
+		if isWordRune(rune(prev)) || prev == '_' || prev == '&' || prev == '/' || prev == '#' || prev == '@' {
+			return false
+		}
+	}
+	if end < len(value) {
+		next := value[end]
+		if isWordRune(rune(next)) || next == '_' || next == '-' || next == '@' {
+			return false
+		}
+	}
+	return true
+}
+
+// isCommitSHA reports whether a hex run looks like a commit rather than a
+// number or a word: it needs both letters and digits
+func isCommitSHA(hex []byte) bool {
+	var letters, digits bool
+	for _, c := range hex {
+		if c >= '0' && c <= '9' {
+			digits = true
+		} else {
+			letters = true
+		}
+	}
+	return letters && digits
+}
+
+// referenceLink creates a link to template with {id} replaced
+func referenceLink(template, id, class string) *ast.Link {
+	link := ast.NewLink()
+	link.Destination = []byte(strings.ReplaceAll(template, "{id}", id))
+	link.SetAttributeString("class", []byte(class))
+	return link
+}
+
+// referenceExtension autolinks references with the templates of refs
+type referenceExtension struct {
+	refs *References
+}
+
+// Extend implements goldmark.Extender
+func (e *referenceExtension) Extend(m goldmark.Markdown) {
+	issue, user, commit := e.refs.templates()
+	if issue == "" && user == "" && commit == "" {
+		return
+	}
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&referenceLinks{issue: issue, user: user, commit: commit}, 100),
+	))
+}
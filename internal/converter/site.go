@@ -0,0 +1,347 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"markdown-to-html/internal/utils"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+)
+
+// DefaultBacklinksTitle is the heading of a page's backlinks section
+const DefaultBacklinksTitle = "Backlinks"
+
+// Site is a set of markdown pages converted to HTML together, so that
+// [[wiki links]] between them resolve and every page lists its backlinks
+type Site struct {
+	Root           string   // Output paths mirror page paths relative to Root
+	Pages          []string // Paths of the markdown files
+	BacklinksTitle string   // Heading of the backlinks section; defaults to DefaultBacklinksTitle
+}
+
+func (s *Site) backlinksTitle() string {
+	if s.BacklinksTitle != "" {
+		return s.BacklinksTitle
+	}
+	return DefaultBacklinksTitle
+}
+
+// LoadSite collects the markdown files under root
+func LoadSite(root string) (*Site, error) {
+	pages, err := utils.GetMarkdownFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no markdown files found in %s", root)
+	}
+	return &Site{Root: root, Pages: pages}, nil
+}
+
+// WikiIssue is a wiki link that did not resolve to exactly one page, or
+// whose heading was not found on the page
+type WikiIssue struct {
+	Page       string   // Page containing the link
+	Line       int      // Line of the link
+	Link       string   // Link target as written
+	Candidates []string // Pages matching an ambiguous link
+}
+
+// String formats the issue as file:line: message
+func (i WikiIssue) String() string {
+	if len(i.Candidates) > 0 {
+		return fmt.Sprintf("%s:%d: ambiguous link [[%s]] matches %s", i.Page, i.Line, i.Link, strings.Join(i.Candidates, ", "))
+	}
+	return fmt.Sprintf("%s:%d: unresolved link [[%s]]", i.Page, i.Line, i.Link)
+}
+
+// sitePage is a parsed page of a site
+type sitePage struct {
+	input     string // Path as given in Site.Pages
+	name      string // Slash separated path relative to the root, without extension
+	output    string
+	title     string
+	doc       *document
+	lines     lineMap // Lines of doc.source in the page file
+	backlinks []*sitePage
+}
+
+// wikiKey normalizes a title or file name for matching: case, spaces,
+// hyphens and underscores do not matter
+func wikiKey(s string) string {
+	s = strings.NewReplacer("-", " ", "_", " ").Replace(strings.ToLower(s))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// wikiIndex finds pages by title, file name or path
+type wikiIndex struct {
+	names map[string][]*sitePage // By title and file name
+	paths map[string]*sitePage   // By path relative to the root
+}
+
+func newWikiIndex(pages []*sitePage) *wikiIndex {
+	index := &wikiIndex{names: make(map[string][]*sitePage), paths: make(map[string]*sitePage)}
+	for _, page := range pages {
+		index.paths[wikiKey(page.name)] = page
+		index.addName(wikiKey(page.title), page)
+		index.addName(wikiKey(path.Base(page.name)), page)
+	}
+	return index
+}
+
+func (x *wikiIndex) addName(key string, page *sitePage) {
+	for _, p := range x.names[key] {
+		if p == page {
+			return
+		}
+	}
+	x.names[key] = append(x.names[key], page)
+}
+
+// resolve finds the pages a link from page to target may mean. Targets
+// with a slash are paths, relative to the linking page or the root.
+func (x *wikiIndex) resolve(page *sitePage, target string) []*sitePage {
+	if target == "" {
+		return []*sitePage{page}
+	}
+	ext := strings.ToLower(path.Ext(target))
+	if ext == ".md" || ext == ".markdown" {
+		target = target[:len(target)-len(ext)]
+	}
+	if strings.Contains(target, "/") {
+		for _, p := range []string{path.Join(path.Dir(page.name), target), path.Clean(strings.TrimPrefix(target, "/"))} {
+			if found, ok := x.paths[wikiKey(p)]; ok {
+				return []*sitePage{found}
+			}
+		}
+		return nil
+	}
+	return x.names[wikiKey(target)]
+}
+
+// link replaces the wiki links of page with links to the pages they
+// resolve to, records backlinks and reports the links that did not resolve
+func (x *wikiIndex) link(page *sitePage) []WikiIssue {
+	var links []*WikiLink
+	_ = ast.Walk(page.doc.root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if link, ok := n.(*WikiLink); ok && entering {
+			links = append(links, link)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var issues []WikiIssue
+	for _, n := range links {
+		issue := WikiIssue{
+			Page: page.input,
+			Line: page.lines.line(bytes.Count(page.doc.source[:n.offset], []byte("\n")) + 1),
+			Link: n.link(),
+		}
+
+		matches := x.resolve(page, n.Target)
+		if len(matches) != 1 {
+			for _, match := range matches {
+				issue.Candidates = append(issue.Candidates, match.input)
+			}
+			sort.Strings(issue.Candidates)
+			issues = append(issues, issue)
+			continue
+		}
+		target := matches[0]
+
+		dest := ""
+		if target != page {
+			dest = pageHref(page, target)
+			target.addBacklink(page)
+		}
+		if n.Fragment != "" {
+			if id, ok := headingFor(target.doc, n.Fragment); ok {
+				dest += "#" + id
+			} else {
+				issues = append(issues, issue)
+			}
+		}
+		if dest == "" {
+			dest = pageHref(page, target)
+		}
+
+		link := ast.NewLink()
+		link.Destination = []byte(dest)
+		link.SetAttributeString("class", []byte(classWikiLink))
+		for child := n.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			link.AppendChild(link, child)
+			child = next
+		}
+		n.Parent().ReplaceChild(n.Parent(), n, link)
+	}
+	return issues
+}
+
+func (p *sitePage) addBacklink(from *sitePage) {
+	for _, b := range p.backlinks {
+		if b == from {
+			return
+		}
+	}
+	p.backlinks = append(p.backlinks, from)
+}
+
+// pageHref returns the relative URL of to's output from from's output
+func pageHref(from, to *sitePage) string {
+	rel, err := filepath.Rel(filepath.Dir(from.output), to.output)
+	if err != nil {
+		rel = to.output
+	}
+	return (&url.URL{Path: filepath.ToSlash(rel)}).String()
+}
+
+// headingFor returns the id of the heading of doc named by fragment, which
+// may be the heading text, its GitHub style anchor or its id
+func headingFor(doc *document, fragment string) (string, bool) {
+	slug := slugify(fragment)
+	for _, heading := range headingRefs(doc) {
+		if heading.id != "" && (heading.slug == slug || heading.id == fragment) {
+			return heading.id, true
+		}
+	}
+	return "", false
+}
+
+// appendBacklinks adds a section listing the pages linking to page
+func appendBacklinks(page *sitePage, title string) {
+	if len(page.backlinks) == 0 {
+		return
+	}
+	sort.SliceStable(page.backlinks, func(i, j int) bool {
+		return strings.ToLower(page.backlinks[i].title) < strings.ToLower(page.backlinks[j].title)
+	})
+
+	section := &Backlinks{}
+	heading := ast.NewHeading(2)
+	heading.AppendChild(heading, ast.NewString([]byte(title)))
+	section.AppendChild(section, heading)
+
+	list := ast.NewList('-')
+	list.IsTight = true
+	for _, from := range page.backlinks {
+		link := ast.NewLink()
+		link.Destination = []byte(pageHref(page, from))
+		link.AppendChild(link, ast.NewString([]byte(from.title)))
+		block := ast.NewTextBlock()
+		block.AppendChild(block, link)
+		item := ast.NewListItem(2)
+		item.AppendChild(item, block)
+		list.AppendChild(list, item)
+	}
+	section.AppendChild(section, list)
+	page.doc.root.AppendChild(page.doc.root, section)
+}
+
+// ConvertSite converts the pages of site to HTML files in outputDir,
+// keeping their layout under site.Root. [[Page Name]] and [[Page
+// Name|label]] links resolve by front matter title, first heading, file
+// name or path, and [[Page#Heading]] links to a heading. Links that match
+// no page or several stay text and are returned as issues. converted, if
+// not nil, is called after each file is written. It stops at the first
+// error.
+func ConvertSite(ctx context.Context, site *Site, outputDir string, opts Options, converted func(inputFile, outputFile string)) ([]WikiIssue, error) {
+	if len(site.Pages) == 0 {
+		return nil, fmt.Errorf("site has no pages")
+	}
+	root, err := filepath.Abs(site.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve site root %s: %w", site.Root, err)
+	}
+
+	// One converter serves the whole site
+	opts.Extenders = append(append([]goldmark.Extender(nil), opts.Extenders...), &wikiExtension{})
+	c, err := NewConverter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]*sitePage, len(site.Pages))
+	for i, input := range site.Pages {
+		abs, err := filepath.Abs(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve page %s: %w", input, err)
+		}
+		if !within(root, abs) {
+			return nil, fmt.Errorf("page %s is outside the site root %s", input, site.Root)
+		}
+		rel, _ := filepath.Rel(root, abs)
+		source, err := os.ReadFile(abs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", input, err)
+		}
+		var lines lineMap
+		if opts.Includes != nil {
+			inc := *opts.Includes
+			inc.Dir = filepath.Dir(abs)
+			if source, lines, err = expandIncludesLines(source, &inc); err != nil {
+				return nil, fmt.Errorf("failed to convert %s: %w", input, err)
+			}
+		}
+
+		name := strings.TrimSuffix(rel, filepath.Ext(rel))
+		page := &sitePage{
+			input:  input,
+			name:   filepath.ToSlash(name),
+			output: filepath.Join(outputDir, name+".html"),
+			doc:    parseWith(c.md, source),
+			lines:  lines,
+		}
+		page.title = resolveMetadata(nil, page.doc).Title
+		if page.title == "" {
+			page.title = filepath.Base(name)
+		}
+		pages[i] = page
+	}
+
+	index := newWikiIndex(pages)
+	var issues []WikiIssue
+	for _, page := range pages {
+		issues = append(issues, index.link(page)...)
+	}
+
+	for _, page := range pages {
+		if ctx.Err() != nil {
+			return issues, contextError(ctx, 0)
+		}
+
+		pageCtx, cancel := withTimeout(ctx, opts.Timeout)
+		err := applyFilters(pageCtx, page.doc, opts, FilterFormatHTML)
+		if err == nil {
+			err = renderDiagrams(pageCtx, page.doc, opts)
+		}
+		cancel()
+		if err != nil {
+			return issues, fmt.Errorf("failed to convert %s: %w", page.input, err)
+		}
+		appendBacklinks(page, site.backlinksTitle())
+
+		var sb strings.Builder
+		if err := c.writeHTML(&sb, page.doc); err != nil {
+			return issues, fmt.Errorf("failed to convert %s: %w", page.input, err)
+		}
+		if err := utils.WriteFile(page.output, sb.String()); err != nil {
+			return issues, fmt.Errorf("failed to write %s: %w", page.output, err)
+		}
+
+		if converted != nil {
+			converted(page.input, page.output)
+		}
+	}
+
+	return issues, nil
+}
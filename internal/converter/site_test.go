@@ -0,0 +1,39 @@
+package converter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWikiIssueLineAfterInclude(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"page.md": "# Page\n{{< include \"part.md\" >}}\n\n[[Missing]]\n",
+		"part.md": "One\n\nTwo [[Gone]]\n\nThree\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	site := &Site{Root: dir, Pages: []string{filepath.Join(dir, "page.md")}}
+	opts := Options{Includes: &Includes{Root: dir}}
+	issues, err := ConvertSite(context.Background(), site, filepath.Join(dir, "out"), opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Links from an included file are reported at the include directive
+	want := map[string]int{"Gone": 2, "Missing": 4}
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d: %v", len(issues), len(want), issues)
+	}
+	for _, issue := range issues {
+		if line, ok := want[issue.Link]; !ok || issue.Line != line {
+			t.Errorf("%s: want line %d", issue, line)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package converter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+)
+
+// readSource reads all markdown from r; goldmark needs the whole source in
+// memory to parse it
+func readSource(r io.Reader) ([]byte, error) {
+	source, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read markdown: %w", err)
+	}
+	return source, nil
+}
+
+// ConvertHTML reads markdown from r and streams the HTML page to w without
+// building the page in memory first. Parsing honours ctx and the
+// per-document timeout from opts.
+func ConvertHTML(ctx context.Context, r io.Reader, w io.Writer, opts Options) error {
+	c, err := NewConverter(opts)
+	if err != nil {
+		return err
+	}
+	return c.ConvertHTML(ctx, r, w)
+}
+
+// ConvertHTML is the package-level ConvertHTML with the Converter's options
+func (c *Converter) ConvertHTML(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := withTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	source, err := readSource(r)
+	if err != nil {
+		return err
+	}
+
+	var doc *document
+	err = runContext(ctx, c.opts.Timeout, func() error {
+		var err error
+		doc, err = c.document(ctx, source)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	buffered := bufio.NewWriter(w)
+	if err := c.writeHTML(buffered, doc); err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write HTML: %w", err)
+	}
+	return nil
+}
+
+// ConvertPDF reads markdown from r and writes the PDF produced by renderer
+// to w. Nothing is written to w on failure.
+func ConvertPDF(ctx context.Context, renderer PDFRenderer, r io.Reader, w io.Writer, opts Options) error {
+	source, err := readSource(r)
+	if err != nil {
+		return err
+	}
+	return RenderPDFContext(ctx, renderer, w, string(source), opts)
+}
+
+// ConvertImage reads markdown from r and writes the rendered image to w.
+// Nothing is written to w on failure.
+func ConvertImage(ctx context.Context, r io.Reader, w io.Writer, opts Options, img ImageOptions) error {
+	source, err := readSource(r)
+	if err != nil {
+		return err
+	}
+	return RenderImageContext(ctx, w, string(source), opts, img)
+}
@@ -0,0 +1,830 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// svgElement is one element of a parsed SVG document
+type svgElement struct {
+	name     string
+	attrs    map[string]string
+	text     string // Character data, including that of tspan children
+	children []*svgElement
+}
+
+// parseSVG reads an svg element into a tree
+func parseSVG(data []byte) (*svgElement, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+
+	var root *svgElement
+	var stack []*svgElement
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SVG: %w", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			el := &svgElement{name: tok.Name.Local, attrs: make(map[string]string, len(tok.Attr))}
+			for _, attr := range tok.Attr {
+				el.attrs[attr.Name.Local] = attr.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, el)
+			} else if root == nil {
+				root = el
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			// Text of tspan children belongs to the enclosing text element
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].name == "text" {
+					stack[i].text += string(tok)
+					break
+				}
+			}
+		}
+	}
+	if root == nil || root.name != "svg" {
+		return nil, fmt.Errorf("failed to parse SVG: no svg element")
+	}
+	return root, nil
+}
+
+// svgMatrix is an affine transform [a b c d e f] mapping (x, y) to
+// (ax + cy + e, bx + dy + f)
+type svgMatrix [6]float64
+
+// then returns the transform applying n first and m second
+func (m svgMatrix) then(n svgMatrix) svgMatrix {
+	return svgMatrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m svgMatrix) apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+// scale is the mean factor the transform scales lengths by
+func (m svgMatrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// parseTransform applies a transform attribute such as
+// "scale(1 1) rotate(0) translate(4 112)" to m
+func parseTransform(m svgMatrix, s string) svgMatrix {
+	for {
+		open := strings.IndexByte(s, '(')
+		end := strings.IndexByte(s, ')')
+		if open < 0 || end < open {
+			return m
+		}
+		name := strings.TrimSpace(strings.Trim(s[:open], " ,"))
+		args := svgNumbers(s[open+1 : end])
+		s = s[end+1:]
+
+		arg := func(i int, def float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return def
+		}
+		switch name {
+		case "matrix":
+			if len(args) == 6 {
+				m = m.then(svgMatrix{args[0], args[1], args[2], args[3], args[4], args[5]})
+			}
+		case "translate":
+			m = m.then(svgMatrix{1, 0, 0, 1, arg(0, 0), arg(1, 0)})
+		case "scale":
+			m = m.then(svgMatrix{arg(0, 1), 0, 0, arg(1, arg(0, 1)), 0, 0})
+		case "rotate":
+			a := arg(0, 0) * math.Pi / 180
+			cx, cy := arg(1, 0), arg(2, 0)
+			m = m.then(svgMatrix{1, 0, 0, 1, cx, cy})
+			m = m.then(svgMatrix{math.Cos(a), math.Sin(a), -math.Sin(a), math.Cos(a), 0, 0})
+			m = m.then(svgMatrix{1, 0, 0, 1, -cx, -cy})
+		}
+	}
+}
+
+// svgNumbers reads the numbers of a list such as "0 0 62 116" or "1,2"
+func svgNumbers(s string) []float64 {
+	var numbers []float64
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' || r == '\n' || r == '\r' }) {
+		if v, err := strconv.ParseFloat(field, 64); err == nil {
+			numbers = append(numbers, v)
+		}
+	}
+	return numbers
+}
+
+// svgLength reads a length in user units, ignoring a px unit
+func svgLength(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "px"), 64)
+	return v
+}
+
+// svgLengthMM converts an absolute length such as "62pt" to mm; plain
+// numbers are CSS pixels. Relative lengths return false.
+func svgLengthMM(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	units := map[string]float64{"px": 25.4 / 96, "pt": 25.4 / 72, "pc": 25.4 / 6, "mm": 1, "cm": 10, "in": 25.4}
+	factor := units["px"]
+	if len(s) > 2 {
+		if f, ok := units[s[len(s)-2:]]; ok {
+			factor = f
+			s = s[:len(s)-2]
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, false
+	}
+	return v * factor, true
+}
+
+// svgColors are the named colours diagram and chart tools commonly write
+var svgColors = map[string][3]int{
+	"black": {0, 0, 0}, "white": {255, 255, 255}, "gray": {128, 128, 128}, "grey": {128, 128, 128},
+	"silver": {192, 192, 192}, "lightgray": {211, 211, 211}, "lightgrey": {211, 211, 211},
+	"darkgray": {169, 169, 169}, "darkgrey": {169, 169, 169}, "dimgray": {105, 105, 105},
+	"red": {255, 0, 0}, "darkred": {139, 0, 0}, "maroon": {128, 0, 0}, "pink": {255, 192, 203},
+	"orange": {255, 165, 0}, "gold": {255, 215, 0}, "yellow": {255, 255, 0}, "lightyellow": {255, 255, 224},
+	"green": {0, 128, 0}, "darkgreen": {0, 100, 0}, "lime": {0, 255, 0}, "lightgreen": {144, 238, 144},
+	"olive": {128, 128, 0}, "teal": {0, 128, 128}, "cyan": {0, 255, 255}, "aqua": {0, 255, 255},
+	"blue": {0, 0, 255}, "darkblue": {0, 0, 139}, "navy": {0, 0, 128}, "lightblue": {173, 216, 230},
+	"steelblue": {70, 130, 180}, "purple": {128, 0, 128}, "magenta": {255, 0, 255}, "fuchsia": {255, 0, 255},
+	"brown": {165, 42, 42}, "beige": {245, 245, 220}, "ivory": {255, 255, 240}, "lavender": {230, 230, 250},
+}
+
+// svgColor parses a paint value; none, transparent and references to
+// gradients or patterns paint nothing
+func svgColor(s string) ([3]int, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case s == "" || s == "none" || s == "transparent" || strings.HasPrefix(s, "url("):
+		return [3]int{}, false
+	case strings.HasPrefix(s, "#"):
+		hex := s[1:]
+		if len(hex) == 3 || len(hex) == 4 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) < 6 {
+			return [3]int{}, false
+		}
+		v, err := strconv.ParseUint(hex[:6], 16, 32)
+		if err != nil {
+			return [3]int{}, false
+		}
+		return [3]int{int(v >> 16), int(v >> 8 & 0xff), int(v & 0xff)}, true
+	case strings.HasPrefix(s, "rgb"):
+		open, end := strings.IndexByte(s, '('), strings.IndexByte(s, ')')
+		if open < 0 || end < open {
+			return [3]int{}, false
+		}
+		var c [3]int
+		for i, part := range strings.Split(s[open+1:end], ",") {
+			if i == 3 {
+				break
+			}
+			part = strings.TrimSpace(part)
+			if strings.HasSuffix(part, "%") {
+				v, _ := strconv.ParseFloat(strings.TrimSuffix(part, "%"), 64)
+				c[i] = int(v * 255 / 100)
+			} else {
+				v, _ := strconv.ParseFloat(part, 64)
+				c[i] = int(v)
+			}
+		}
+		return c, true
+	}
+	if c, ok := svgColors[s]; ok {
+		return c, true
+	}
+	return [3]int{}, true
+}
+
+// svgStyle is the inherited presentation state of an element
+type svgStyle struct {
+	fill          string
+	stroke        string
+	strokeWidth   float64
+	dash          []float64
+	opacity       float64
+	fillOpacity   float64
+	strokeOpacity float64
+	fillRule      string
+	fontSize      float64
+	fontFamily    string
+	fontWeight    string
+	fontStyle     string
+	anchor        string
+}
+
+var svgDefaultStyle = svgStyle{
+	fill:          "black",
+	stroke:        "none",
+	strokeWidth:   1,
+	opacity:       1,
+	fillOpacity:   1,
+	strokeOpacity: 1,
+	fontSize:      16,
+	anchor:        "start",
+}
+
+// inherit returns the style of el given its parent's style. The style
+// attribute wins over presentation attributes.
+func (st svgStyle) inherit(el *svgElement) svgStyle {
+	st.opacity = 1 // Group opacity is multiplied in by the drawer
+	props := make(map[string]string)
+	for name, value := range el.attrs {
+		props[name] = value
+	}
+	for _, decl := range strings.Split(el.attrs["style"], ";") {
+		if name, value, ok := strings.Cut(decl, ":"); ok {
+			props[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+
+	for name, value := range props {
+		number := func() (float64, bool) {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "px"), 64)
+			return v, err == nil
+		}
+		switch name {
+		case "fill":
+			st.fill = value
+		case "stroke":
+			st.stroke = value
+		case "stroke-width":
+			if v, ok := number(); ok {
+				st.strokeWidth = v
+			}
+		case "stroke-dasharray":
+			st.dash = nil
+			if value != "none" {
+				st.dash = svgNumbers(value)
+			}
+		case "opacity":
+			if v, ok := number(); ok {
+				st.opacity = v
+			}
+		case "fill-opacity":
+			if v, ok := number(); ok {
+				st.fillOpacity = v
+			}
+		case "stroke-opacity":
+			if v, ok := number(); ok {
+				st.strokeOpacity = v
+			}
+		case "fill-rule":
+			st.fillRule = value
+		case "font-size":
+			if v, ok := number(); ok {
+				st.fontSize = v
+			} else if v, err := strconv.ParseFloat(strings.TrimSuffix(value, "pt"), 64); err == nil {
+				st.fontSize = v * 4 / 3
+			}
+		case "font-family":
+			st.fontFamily = value
+		case "font-weight":
+			st.fontWeight = value
+		case "font-style":
+			st.fontStyle = value
+		case "text-anchor":
+			st.anchor = value
+		}
+	}
+	return st
+}
+
+// svgDrawer draws a parsed SVG document with fpdf path operators
+type svgDrawer struct {
+	pdf       *fpdf.Fpdf
+	sans      string
+	mono      string
+	translate func(string) string
+}
+
+// svgSize returns the natural size of an svg element in mm and the
+// transform from its user units to that size
+func svgSize(root *svgElement) (width, height float64, m svgMatrix) {
+	box := svgNumbers(root.attrs["viewBox"])
+	width, okWidth := svgLengthMM(root.attrs["width"])
+	height, okHeight := svgLengthMM(root.attrs["height"])
+
+	if len(box) != 4 || box[2] <= 0 || box[3] <= 0 {
+		if !okWidth || !okHeight {
+			width, height = 100, 75
+		}
+		px := 25.4 / 96
+		return width, height, svgMatrix{px, 0, 0, px, 0, 0}
+	}
+
+	switch {
+	case !okWidth && !okHeight:
+		width, height = box[2]*25.4/96, box[3]*25.4/96
+	case !okWidth:
+		width = height * box[2] / box[3]
+	case !okHeight:
+		height = width * box[3] / box[2]
+	}
+	sx, sy := width/box[2], height/box[3]
+	return width, height, svgMatrix{sx, 0, 0, sy, -box[0] * sx, -box[1] * sy}
+}
+
+// draw draws el and its children with m mapping user units to page mm
+func (d *svgDrawer) draw(el *svgElement, st svgStyle, m svgMatrix, opacity float64) {
+	switch el.name {
+	case "defs", "title", "desc", "style", "metadata", "clipPath", "mask", "marker", "symbol", "pattern",
+		"linearGradient", "radialGradient", "filter", "script", "image", "use", "foreignObject":
+		return
+	}
+	if el.attrs["display"] == "none" || el.attrs["visibility"] == "hidden" || strings.Contains(el.attrs["style"], "display:none") {
+		return
+	}
+
+	st = st.inherit(el)
+	opacity *= st.opacity
+	if transform, ok := el.attrs["transform"]; ok {
+		m = parseTransform(m, transform)
+	}
+
+	switch el.name {
+	case "svg", "g", "a", "switch":
+		for _, child := range el.children {
+			d.draw(child, st, m, opacity)
+		}
+	case "text":
+		d.text(el, st, m, opacity)
+	default:
+		if path := svgShape(el); len(path) > 0 {
+			d.path(path, st, m, opacity)
+		}
+	}
+}
+
+// svgSegment is one absolute path command: M, L, C or Z with its points
+type svgSegment struct {
+	op     byte
+	points []float64
+}
+
+// svgShape returns the outline of a basic shape or path element
+func svgShape(el *svgElement) []svgSegment {
+	attr := func(name string) float64 { return svgLength(el.attrs[name]) }
+	switch el.name {
+	case "path":
+		return parsePathData(el.attrs["d"])
+	case "rect":
+		x, y, w, h := attr("x"), attr("y"), attr("width"), attr("height")
+		if w <= 0 || h <= 0 {
+			return nil
+		}
+		return []svgSegment{
+			{'M', []float64{x, y}}, {'L', []float64{x + w, y}}, {'L', []float64{x + w, y + h}},
+			{'L', []float64{x, y + h}}, {'Z', nil},
+		}
+	case "circle":
+		return ellipsePath(attr("cx"), attr("cy"), attr("r"), attr("r"))
+	case "ellipse":
+		return ellipsePath(attr("cx"), attr("cy"), attr("rx"), attr("ry"))
+	case "line":
+		return []svgSegment{{'M', []float64{attr("x1"), attr("y1")}}, {'L', []float64{attr("x2"), attr("y2")}}}
+	case "polyline", "polygon":
+		points := svgNumbers(el.attrs["points"])
+		var path []svgSegment
+		for i := 0; i+1 < len(points); i += 2 {
+			op := byte('L')
+			if i == 0 {
+				op = 'M'
+			}
+			path = append(path, svgSegment{op, points[i : i+2]})
+		}
+		if el.name == "polygon" && len(path) > 0 {
+			path = append(path, svgSegment{'Z', nil})
+		}
+		return path
+	}
+	return nil
+}
+
+// ellipsePath approximates an ellipse with four cubic curves
+func ellipsePath(cx, cy, rx, ry float64) []svgSegment {
+	if rx <= 0 || ry <= 0 {
+		return nil
+	}
+	const k = 0.5523 // Control point distance for a quarter circle
+	return []svgSegment{
+		{'M', []float64{cx + rx, cy}},
+		{'C', []float64{cx + rx, cy + k*ry, cx + k*rx, cy + ry, cx, cy + ry}},
+		{'C', []float64{cx - k*rx, cy + ry, cx - rx, cy + k*ry, cx - rx, cy}},
+		{'C', []float64{cx - rx, cy - k*ry, cx - k*rx, cy - ry, cx, cy - ry}},
+		{'C', []float64{cx + k*rx, cy - ry, cx + rx, cy - k*ry, cx + rx, cy}},
+		{'Z', nil},
+	}
+}
+
+// parsePathData converts path data to absolute M, L, C and Z commands
+func parsePathData(d string) []svgSegment {
+	var path []svgSegment
+	var x, y, startX, startY float64 // Current point and subpath start
+	var lastCtrlX, lastCtrlY float64 // Last control point for S and T
+	var lastOp byte
+
+	i := 0
+	skip := func() {
+		for i < len(d) && (d[i] == ' ' || d[i] == ',' || d[i] == '\t' || d[i] == '\n' || d[i] == '\r') {
+			i++
+		}
+	}
+	number := func() (float64, bool) {
+		skip()
+		start := i
+		if i < len(d) && (d[i] == '-' || d[i] == '+') {
+			i++
+		}
+		dot := false
+		for i < len(d) && (d[i] >= '0' && d[i] <= '9' || d[i] == '.' && !dot) {
+			if d[i] == '.' {
+				dot = true
+			}
+			i++
+		}
+		if i < len(d) && (d[i] == 'e' || d[i] == 'E') {
+			i++
+			if i < len(d) && (d[i] == '-' || d[i] == '+') {
+				i++
+			}
+			for i < len(d) && d[i] >= '0' && d[i] <= '9' {
+				i++
+			}
+		}
+		v, err := strconv.ParseFloat(d[start:i], 64)
+		if err != nil {
+			i = start
+			return 0, false
+		}
+		return v, true
+	}
+	numbers := func(n int) ([]float64, bool) {
+		values := make([]float64, n)
+		for j := range values {
+			v, ok := number()
+			if !ok {
+				return nil, false
+			}
+			values[j] = v
+		}
+		return values, true
+	}
+	flag := func() (bool, bool) {
+		skip()
+		if i < len(d) && (d[i] == '0' || d[i] == '1') {
+			i++
+			return d[i-1] == '1', true
+		}
+		return false, false
+	}
+
+	var op byte
+	for {
+		skip()
+		if i >= len(d) {
+			return path
+		}
+		if c := d[i]; c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' {
+			op = c
+			i++
+		} else if op == 0 {
+			return path
+		}
+
+		rel := op >= 'a'
+		ox, oy := 0.0, 0.0
+		if rel {
+			ox, oy = x, y
+		}
+		upper := op &^ 0x20
+
+		switch upper {
+		case 'Z':
+			path = append(path, svgSegment{'Z', nil})
+			x, y = startX, startY
+			lastOp = 'Z'
+			op = 0
+			continue
+		case 'M', 'L', 'T':
+			v, ok := numbers(2)
+			if !ok {
+				return path
+			}
+			px, py := ox+v[0], oy+v[1]
+			switch {
+			case upper == 'M':
+				path = append(path, svgSegment{'M', []float64{px, py}})
+				startX, startY = px, py
+				// Further pairs are implicit line commands
+				if rel {
+					op = 'l'
+				} else {
+					op = 'L'
+				}
+			case upper == 'T':
+				cx, cy := x, y
+				if lastOp == 'Q' || lastOp == 'T' {
+					cx, cy = 2*x-lastCtrlX, 2*y-lastCtrlY
+				}
+				path = append(path, quadratic(x, y, cx, cy, px, py))
+				lastCtrlX, lastCtrlY = cx, cy
+			default:
+				path = append(path, svgSegment{'L', []float64{px, py}})
+			}
+			x, y = px, py
+		case 'H', 'V':
+			v, ok := number()
+			if !ok {
+				return path
+			}
+			if upper == 'H' {
+				x = ox + v
+			} else {
+				y = oy + v
+			}
+			path = append(path, svgSegment{'L', []float64{x, y}})
+		case 'C', 'S':
+			n := 6
+			if upper == 'S' {
+				n = 4
+			}
+			v, ok := numbers(n)
+			if !ok {
+				return path
+			}
+			if upper == 'S' {
+				c1x, c1y := x, y
+				if lastOp == 'C' || lastOp == 'S' {
+					c1x, c1y = 2*x-lastCtrlX, 2*y-lastCtrlY
+				}
+				v = append([]float64{c1x - ox, c1y - oy}, v...)
+			}
+			points := []float64{ox + v[0], oy + v[1], ox + v[2], oy + v[3], ox + v[4], oy + v[5]}
+			path = append(path, svgSegment{'C', points})
+			lastCtrlX, lastCtrlY = points[2], points[3]
+			x, y = points[4], points[5]
+		case 'Q':
+			v, ok := numbers(4)
+			if !ok {
+				return path
+			}
+			cx, cy, px, py := ox+v[0], oy+v[1], ox+v[2], oy+v[3]
+			path = append(path, quadratic(x, y, cx, cy, px, py))
+			lastCtrlX, lastCtrlY = cx, cy
+			x, y = px, py
+		case 'A':
+			r, ok := numbers(3)
+			if !ok {
+				return path
+			}
+			large, ok1 := flag()
+			sweep, ok2 := flag()
+			v, ok3 := numbers(2)
+			if !ok1 || !ok2 || !ok3 {
+				return path
+			}
+			px, py := ox+v[0], oy+v[1]
+			path = append(path, arcPath(x, y, r[0], r[1], r[2], large, sweep, px, py)...)
+			x, y = px, py
+		default:
+			return path
+		}
+		lastOp = upper
+	}
+}
+
+// quadratic converts a quadratic curve to a cubic one
+func quadratic(x0, y0, cx, cy, x, y float64) svgSegment {
+	return svgSegment{'C', []float64{
+		x0 + 2*(cx-x0)/3, y0 + 2*(cy-y0)/3,
+		x + 2*(cx-x)/3, y + 2*(cy-y)/3,
+		x, y,
+	}}
+}
+
+// arcPath converts an elliptical arc to cubic curves, following the
+// endpoint to center conversion of the SVG specification
+func arcPath(x1, y1, rx, ry, angle float64, large, sweep bool, x2, y2 float64) []svgSegment {
+	if x1 == x2 && y1 == y2 {
+		return nil
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return []svgSegment{{'L', []float64{x2, y2}}}
+	}
+
+	phi := angle * math.Pi / 180
+	cos, sin := math.Cos(phi), math.Sin(phi)
+	dx, dy := (x1-x2)/2, (y1-y2)/2
+	x1p, y1p := cos*dx+sin*dy, -sin*dx+cos*dy
+
+	// Scale up radii that are too small to reach the end point
+	if lambda := x1p*x1p/(rx*rx) + y1p*y1p/(ry*ry); lambda > 1 {
+		rx, ry = rx*math.Sqrt(lambda), ry*math.Sqrt(lambda)
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1p*y1p - ry*ry*x1p*x1p
+	den := rx*rx*y1p*y1p + ry*ry*x1p*x1p
+	coef := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		coef = -coef
+	}
+	cxp, cyp := coef*rx*y1p/ry, -coef*ry*x1p/rx
+	cx := cos*cxp - sin*cyp + (x1+x2)/2
+	cy := sin*cxp + cos*cyp + (y1+y2)/2
+
+	start := math.Atan2((y1p-cyp)/ry, (x1p-cxp)/rx)
+	delta := math.Atan2((-y1p-cyp)/ry, (-x1p-cxp)/rx) - start
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+
+	// One curve per quarter turn at most
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(n)
+	k := 4.0 / 3 * math.Tan(step/4)
+	point := func(t float64) (float64, float64, float64, float64) {
+		ex, ey := rx*math.Cos(t), ry*math.Sin(t)
+		tx, ty := -rx*math.Sin(t), ry*math.Cos(t)
+		return cx + cos*ex - sin*ey, cy + sin*ex + cos*ey, cos*tx - sin*ty, sin*tx + cos*ty
+	}
+
+	var path []svgSegment
+	t := start
+	px, py, pdx, pdy := point(t)
+	for i := 0; i < n; i++ {
+		t += step
+		qx, qy, qdx, qdy := point(t)
+		path = append(path, svgSegment{'C', []float64{px + k*pdx, py + k*pdy, qx - k*qdx, qy - k*qdy, qx, qy}})
+		px, py, pdx, pdy = qx, qy, qdx, qdy
+	}
+	// Land exactly on the end point
+	last := path[len(path)-1].points
+	last[4], last[5] = x2, y2
+	return path
+}
+
+// path fills and strokes an outline
+func (d *svgDrawer) path(path []svgSegment, st svgStyle, m svgMatrix, opacity float64) {
+	pdf := d.pdf
+	fill, doFill := svgColor(st.fill)
+	stroke, doStroke := svgColor(st.stroke)
+	doStroke = doStroke && st.strokeWidth > 0
+	if !doFill && !doStroke {
+		return
+	}
+
+	for _, seg := range path {
+		switch seg.op {
+		case 'M':
+			pdf.MoveTo(m.apply(seg.points[0], seg.points[1]))
+		case 'L':
+			pdf.LineTo(m.apply(seg.points[0], seg.points[1]))
+		case 'C':
+			c1x, c1y := m.apply(seg.points[0], seg.points[1])
+			c2x, c2y := m.apply(seg.points[2], seg.points[3])
+			x, y := m.apply(seg.points[4], seg.points[5])
+			pdf.CurveBezierCubicTo(c1x, c1y, c2x, c2y, x, y)
+		case 'Z':
+			pdf.ClosePath()
+		}
+	}
+
+	style := ""
+	if doFill {
+		pdf.SetFillColor(fill[0], fill[1], fill[2])
+		style += "F"
+	}
+	if doStroke {
+		pdf.SetDrawColor(stroke[0], stroke[1], stroke[2])
+		pdf.SetLineWidth(st.strokeWidth * m.scale())
+		style += "D"
+		if len(st.dash) > 0 {
+			dash := make([]float64, len(st.dash))
+			for i, v := range st.dash {
+				dash[i] = v * m.scale()
+			}
+			pdf.SetDashPattern(dash, 0)
+		}
+	}
+	if st.fillRule == "evenodd" && doFill {
+		style += "*"
+	}
+
+	alpha := opacity
+	if doFill && !doStroke {
+		alpha *= st.fillOpacity
+	} else if doStroke && !doFill {
+		alpha *= st.strokeOpacity
+	}
+	if alpha < 1 {
+		pdf.SetAlpha(alpha, "Normal")
+	}
+	pdf.DrawPath(style)
+	if alpha < 1 {
+		pdf.SetAlpha(1, "Normal")
+	}
+	if doStroke && len(st.dash) > 0 {
+		pdf.SetDashPattern([]float64{}, 0)
+	}
+}
+
+// text writes a text element. Rotation and skew are not applied to the
+// glyphs, only to the anchor point.
+func (d *svgDrawer) text(el *svgElement, st svgStyle, m svgMatrix, opacity float64) {
+	pdf := d.pdf
+	content := strings.Join(strings.Fields(el.text), " ")
+	fill, ok := svgColor(st.fill)
+	if content == "" || !ok {
+		return
+	}
+
+	// Position comes from the element or its first tspan
+	xAttr, yAttr := el.attrs["x"], el.attrs["y"]
+	for _, child := range el.children {
+		if child.name == "tspan" {
+			if xAttr == "" {
+				xAttr = child.attrs["x"]
+			}
+			if yAttr == "" {
+				yAttr = child.attrs["y"]
+			}
+			break
+		}
+	}
+	x, y := m.apply(svgLength(firstField(xAttr)), svgLength(firstField(yAttr)))
+
+	family := d.sans
+	if name := strings.ToLower(st.fontFamily); strings.Contains(name, "mono") || strings.Contains(name, "courier") {
+		family = d.mono
+	}
+	style := ""
+	if weight := st.fontWeight; weight == "bold" || weight == "bolder" || weight >= "600" && weight <= "900" {
+		style += "B"
+	}
+	if st.fontStyle == "italic" || st.fontStyle == "oblique" {
+		style += "I"
+	}
+	size := st.fontSize * m.scale()
+	pdf.SetFont(family, style, size/0.3528)
+
+	content = d.translate(content)
+	switch st.anchor {
+	case "middle":
+		x -= pdf.GetStringWidth(content) / 2
+	case "end":
+		x -= pdf.GetStringWidth(content)
+	}
+
+	pdf.SetTextColor(fill[0], fill[1], fill[2])
+	if alpha := opacity * st.fillOpacity; alpha < 1 {
+		pdf.SetAlpha(alpha, "Normal")
+		defer pdf.SetAlpha(1, "Normal")
+	}
+	pdf.Text(x, y, content)
+}
+
+// firstField returns the first value of a list such as the x attribute of
+// a text element
+func firstField(s string) string {
+	if fields := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark"
+	meta "github.com/yuin/goldmark-meta"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// typographer is goldmark's typographer with apostrophes written as a
+// numeric entity, so they stay apart from closing single quotes when the
+// quotes are localized
+var typographer = extension.NewTypographer(extension.WithTypographicSubstitutions(map[extension.TypographicPunctuation]string{
+	extension.Apostrophe: "&#8217;",
+}))
+
+// quoteMarks are the opening and closing quotation marks of a language
+type quoteMarks struct {
+	leftDouble, rightDouble string
+	leftSingle, rightSingle string
+}
+
+var (
+	englishQuotes   = quoteMarks{"&ldquo;", "&rdquo;", "&lsquo;", "&rsquo;"}
+	germanQuotes    = quoteMarks{"&bdquo;", "&ldquo;", "&sbquo;", "&lsquo;"}
+	guillemets      = quoteMarks{"&laquo;", "&raquo;", "&lsaquo;", "&rsaquo;"}
+	frenchQuotes    = quoteMarks{"&laquo;&#8239;", "&#8239;&raquo;", "&lsaquo;&#8239;", "&#8239;&rsaquo;"}
+	romanceQuotes   = quoteMarks{"&laquo;", "&raquo;", "&ldquo;", "&rdquo;"}
+	slavicQuotes    = quoteMarks{"&laquo;", "&raquo;", "&bdquo;", "&ldquo;"}
+	polishQuotes    = quoteMarks{"&bdquo;", "&rdquo;", "&laquo;", "&raquo;"}
+	nordicQuotes    = quoteMarks{"&rdquo;", "&rdquo;", "&rsquo;", "&rsquo;"}
+	danishQuotes    = quoteMarks{"&raquo;", "&laquo;", "&rsaquo;", "&lsaquo;"}
+	japaneseQuotes  = quoteMarks{"&#12300;", "&#12301;", "&#12302;", "&#12303;"}
+	hungarianQuotes = quoteMarks{"&bdquo;", "&rdquo;", "&raquo;", "&laquo;"}
+)
+
+// languageQuotes maps language tags, or their primary subtag, to quotation
+// marks
+var languageQuotes = map[string]quoteMarks{
+	"en":    englishQuotes,
+	"tr":    englishQuotes,
+	"nl":    englishQuotes,
+	"zh":    englishQuotes,
+	"de":    germanQuotes,
+	"cs":    germanQuotes,
+	"sk":    germanQuotes,
+	"de-ch": guillemets,
+	"fr-ch": guillemets,
+	"fr":    frenchQuotes,
+	"es":    romanceQuotes,
+	"it":    romanceQuotes,
+	"pt":    romanceQuotes,
+	"el":    romanceQuotes,
+	"ru":    slavicQuotes,
+	"uk":    slavicQuotes,
+	"be":    slavicQuotes,
+	"pl":    polishQuotes,
+	"ro":    polishQuotes,
+	"sv":    nordicQuotes,
+	"fi":    nordicQuotes,
+	"da":    danishQuotes,
+	"ja":    japaneseQuotes,
+	"hu":    hungarianQuotes,
+}
+
+// quotesFor returns the quotation marks for a language tag such as "de" or
+// "de-CH"
+func quotesFor(lang string) (quoteMarks, bool) {
+	lang = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+	if quotes, ok := languageQuotes[lang]; ok {
+		return quotes, true
+	}
+	primary, _, _ := strings.Cut(lang, "-")
+	quotes, ok := languageQuotes[primary]
+	return quotes, ok
+}
+
+// localQuotes replaces the English quotes of the typographer with those of
+// the document language: the configured one, else the front matter lang
+type localQuotes struct {
+	language string
+}
+
+// Transform implements parser.ASTTransformer
+func (t *localQuotes) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	lang := t.language
+	if lang == "" {
+		lang = metaLanguageTag(meta.Get(pc))
+	}
+	quotes, ok := quotesFor(lang)
+	if !ok || quotes == englishQuotes {
+		return
+	}
+
+	replacements := map[string]string{
+		englishQuotes.leftDouble:  quotes.leftDouble,
+		englishQuotes.rightDouble: quotes.rightDouble,
+		englishQuotes.leftSingle:  quotes.leftSingle,
+		englishQuotes.rightSingle: quotes.rightSingle,
+	}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if s, ok := n.(*ast.String); ok && entering && s.IsCode() {
+			if quote, ok := replacements[string(s.Value)]; ok {
+				// Value is shared with the typographer; replace, never modify
+				s.Value = []byte(quote)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// localQuotesExtension localizes typographer quotes to language
+type localQuotesExtension struct {
+	language string
+}
+
+// Extend implements goldmark.Extender
+func (e *localQuotesExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&localQuotes{language: e.language}, 100),
+	))
+}
@@ -0,0 +1,236 @@
+package converter
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Watermark positions
+const (
+	WatermarkCenter = "center"
+	WatermarkTop    = "top"
+	WatermarkBottom = "bottom"
+)
+
+// DefaultWatermarkRotation is the rotation applied when none is configured
+const DefaultWatermarkRotation = 45.0
+
+// Front matter keys controlling watermarks
+const (
+	metaStatus    = "status"
+	metaWatermark = "watermark"
+)
+
+// statusWatermarks maps front matter status values to stamp texts
+var statusWatermarks = map[string]string{
+	"draft":        "DRAFT",
+	"taslak":       "TASLAK",
+	"review":       "REVIEW",
+	"confidential": "CONFIDENTIAL",
+	"gizli":        "GİZLİ",
+}
+
+// Watermark stamps every page with text or an image
+type Watermark struct {
+	Text     string  // Stamp text, e.g. "DRAFT"
+	Image    string  // Path to a PNG or JPEG used instead of Text
+	Opacity  float64 // 0 to 1; defaults to 0.15
+	Rotation float64 // Degrees counter-clockwise; defaults to 45
+	Position string  // center, top or bottom; defaults to center
+}
+
+// withDefaults fills unset fields
+func (wm Watermark) withDefaults() Watermark {
+	if wm.Opacity <= 0 || wm.Opacity > 1 {
+		wm.Opacity = 0.15
+	}
+	if wm.Position == "" {
+		wm.Position = WatermarkCenter
+	}
+	return wm
+}
+
+// resolveWatermark picks the watermark for a document: explicit options win,
+// then a front matter watermark entry, then a stamp derived from status.
+// Front matter comes from the document, so its image must lie inside the
+// include root and is ignored when inc is nil.
+func resolveWatermark(configured *Watermark, metadata map[string]interface{}, inc *Includes) (*Watermark, error) {
+	if configured != nil {
+		wm := configured.withDefaults()
+		return &wm, nil
+	}
+
+	switch v := metadata[metaWatermark].(type) {
+	case string:
+		wm := Watermark{Text: v, Rotation: DefaultWatermarkRotation}.withDefaults()
+		return &wm, nil
+	case map[interface{}]interface{}:
+		wm := Watermark{Rotation: DefaultWatermarkRotation}
+		for key, value := range v {
+			switch fmt.Sprint(key) {
+			case "text":
+				wm.Text = fmt.Sprint(value)
+			case "image":
+				wm.Image = fmt.Sprint(value)
+			case "opacity":
+				if f, ok := toFloat(value); ok {
+					wm.Opacity = f
+				}
+			case "rotation":
+				if f, ok := toFloat(value); ok {
+					wm.Rotation = f
+				}
+			case "position":
+				wm.Position = fmt.Sprint(value)
+			}
+		}
+		if wm.Image != "" {
+			if inc == nil {
+				wm.Image = ""
+			} else {
+				image, err := inc.file(wm.Image)
+				if err != nil {
+					return nil, fmt.Errorf("invalid watermark image: %w", err)
+				}
+				wm.Image = image
+			}
+		}
+		wm = wm.withDefaults()
+		return &wm, nil
+	}
+
+	if status, ok := metadata[metaStatus].(string); ok {
+		if text, ok := statusWatermarks[strings.ToLower(strings.TrimSpace(status))]; ok {
+			wm := Watermark{Text: text, Rotation: DefaultWatermarkRotation}.withDefaults()
+			return &wm, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// watermarkHTML returns the overlay markup and its CSS. The overlay is
+// position: fixed, which browsers and wkhtmltopdf repeat on every printed
+// page.
+func watermarkHTML(wm *Watermark) (string, string, error) {
+	if wm == nil || (wm.Text == "" && wm.Image == "") {
+		return "", "", nil
+	}
+
+	var content string
+	if wm.Image != "" {
+		data, err := os.ReadFile(wm.Image)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read watermark image: %w", err)
+		}
+		mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(wm.Image)))
+		if mimeType == "" {
+			mimeType = "image/png"
+		}
+		content = fmt.Sprintf(`<img src="data:%s;base64,%s" alt="" />`, mimeType, base64.StdEncoding.EncodeToString(data))
+	} else {
+		content = "<span>" + html.EscapeString(wm.Text) + "</span>"
+	}
+
+	placement := "top: 45%;"
+	switch wm.Position {
+	case WatermarkTop:
+		placement = "top: 8%;"
+	case WatermarkBottom:
+		placement = "bottom: 8%;"
+	}
+
+	css := fmt.Sprintf(`
+		.watermark {
+			position: fixed;
+			left: 0;
+			right: 0;
+			%s
+			z-index: 9999;
+			text-align: center;
+			pointer-events: none;
+			opacity: %.2f;
+			-webkit-transform: rotate(%.1fdeg);
+			transform: rotate(%.1fdeg);
+		}
+		.watermark span {
+			font-size: 110px;
+			font-weight: 700;
+			letter-spacing: 0.1em;
+			white-space: nowrap;
+		}
+		.watermark img {
+			max-width: 60%%;
+		}
+		@media print {
+			.watermark {
+				-webkit-print-color-adjust: exact;
+				print-color-adjust: exact;
+			}
+		}`, placement, wm.Opacity, -wm.Rotation, -wm.Rotation)
+
+	return `<div class="watermark">` + content + "</div>\n", css, nil
+}
+
+// drawWatermark stamps the current fpdf page; it is called from the page
+// header so the stamp sits underneath the content
+func drawWatermark(pdf *fpdf.Fpdf, wm *Watermark, color [3]int, translate func(string) string, family string) {
+	width, height := pdf.GetPageSize()
+	cx, cy := width/2, height*0.5
+	switch wm.Position {
+	case WatermarkTop:
+		cy = height * 0.15
+	case WatermarkBottom:
+		cy = height * 0.85
+	}
+
+	pdf.SetAlpha(wm.Opacity, "Normal")
+	pdf.TransformBegin()
+	pdf.TransformRotate(wm.Rotation, cx, cy)
+
+	if wm.Image != "" {
+		options := fpdf.ImageOptions{ImageType: localImageType(wm.Image), ReadDpi: true}
+		if options.ImageType != "" {
+			info := pdf.RegisterImageOptions(wm.Image, options)
+			if info != nil {
+				w, h := info.Extent()
+				if max := width * 0.6; w > max {
+					h = h * max / w
+					w = max
+				}
+				pdf.ImageOptions(wm.Image, cx-w/2, cy-h/2, w, h, false, options, 0, "")
+			}
+		}
+	} else {
+		// Shrink long stamps so they stay on the page
+		text := translate(wm.Text)
+		size := 80.0
+		pdf.SetFont(family, "B", size)
+		if textWidth := pdf.GetStringWidth(text); textWidth > width*0.9 {
+			size = size * width * 0.9 / textWidth
+			pdf.SetFont(family, "B", size)
+		}
+		pdf.SetTextColor(color[0], color[1], color[2])
+		pdf.Text(cx-pdf.GetStringWidth(text)/2, cy+size*0.3528/3, text)
+	}
+
+	pdf.TransformEnd()
+	pdf.SetAlpha(1, "Normal")
+}
@@ -0,0 +1,206 @@
+package converter
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindWikiLink is the node kind of a [[wiki link]]
+var KindWikiLink = ast.NewNodeKind("WikiLink")
+
+// WikiLink is a [[Page Name]] or [[Page Name#Heading|label]] link. Site
+// conversion replaces resolved ones with regular links; the rest render as
+// marked text. Its children are the label.
+type WikiLink struct {
+	ast.BaseInline
+
+	// Target is the page title, file name or path as written
+	Target string
+
+	// Fragment is the heading after '#', if any
+	Fragment string
+
+	offset int // Position of the opening brackets in the source
+}
+
+// Kind implements ast.Node
+func (n *WikiLink) Kind() ast.NodeKind {
+	return KindWikiLink
+}
+
+// Dump implements ast.Node
+func (n *WikiLink) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Target":   n.Target,
+		"Fragment": n.Fragment,
+	}, nil)
+}
+
+// link returns the link as written, without the brackets and label
+func (n *WikiLink) link() string {
+	if n.Fragment == "" {
+		return n.Target
+	}
+	return n.Target + "#" + n.Fragment
+}
+
+// wikiLinkParser parses [[target]] and [[target|label]]
+type wikiLinkParser struct{}
+
+// Trigger implements parser.InlineParser
+func (p *wikiLinkParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+// Parse implements parser.InlineParser
+func (p *wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	if len(line) < 5 || line[1] != '[' {
+		return nil
+	}
+	end := bytes.Index(line[2:], []byte("]]"))
+	if end < 0 {
+		return nil
+	}
+	inner := line[2 : 2+end]
+	if bytes.ContainsAny(inner, "[]\n") {
+		return nil
+	}
+
+	// Offsets into line of the target and the label
+	targetStart, targetStop := 2, 2+end
+	labelStart, labelStop := targetStart, targetStop
+	if bar := bytes.IndexByte(inner, '|'); bar >= 0 {
+		targetStop = 2 + bar
+		labelStart = targetStop + 1
+	}
+	targetStart, targetStop = trimSpaces(line, targetStart, targetStop)
+	labelStart, labelStop = trimSpaces(line, labelStart, labelStop)
+	if targetStart == targetStop || labelStart == labelStop {
+		return nil
+	}
+
+	target := string(line[targetStart:targetStop])
+	n := &WikiLink{offset: segment.Start}
+	if hash := bytes.IndexByte(line[targetStart:targetStop], '#'); hash >= 0 {
+		n.Target = trimSpace(target[:hash])
+		n.Fragment = trimSpace(target[hash+1:])
+	} else {
+		n.Target = target
+	}
+	if n.Target == "" && n.Fragment == "" {
+		return nil
+	}
+
+	n.AppendChild(n, ast.NewTextSegment(text.NewSegment(segment.Start+labelStart, segment.Start+labelStop)))
+	block.Advance(end + 4)
+	return n
+}
+
+// trimSpaces narrows line[start:stop] to exclude surrounding spaces
+func trimSpaces(line []byte, start, stop int) (int, int) {
+	for start < stop && util.IsSpace(line[start]) {
+		start++
+	}
+	for stop > start && util.IsSpace(line[stop-1]) {
+		stop--
+	}
+	return start, stop
+}
+
+// trimSpace is trimSpaces for strings
+func trimSpace(s string) string {
+	start, stop := trimSpaces([]byte(s), 0, len(s))
+	return s[start:stop]
+}
+
+// KindBacklinks is the node kind of a page's backlinks section
+var KindBacklinks = ast.NewNodeKind("Backlinks")
+
+// Backlinks lists the pages linking to a page. Its children are a heading
+// and a list of links.
+type Backlinks struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node
+func (n *Backlinks) Kind() ast.NodeKind {
+	return KindBacklinks
+}
+
+// Dump implements ast.Node
+func (n *Backlinks) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// Classes of wiki link markup
+const (
+	classWikiLink    = "wiki-link"
+	classWikiMissing = "wiki-missing"
+	classBacklinks   = "backlinks"
+)
+
+// wikiHTMLRenderer writes unresolved wiki links and backlinks sections
+type wikiHTMLRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *wikiHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindWikiLink, r.renderWikiLink)
+	reg.Register(KindBacklinks, r.renderBacklinks)
+}
+
+func (r *wikiHTMLRenderer) renderWikiLink(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(`<span class="` + classWikiLink + " " + classWikiMissing + `">`)
+	} else {
+		_, _ = w.WriteString("</span>")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *wikiHTMLRenderer) renderBacklinks(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(`<nav class="` + classBacklinks + `">` + "\n")
+	} else {
+		_, _ = w.WriteString("</nav>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+// wikiExtension parses [[wiki links]]; ConvertSite resolves them
+type wikiExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *wikiExtension) Extend(m goldmark.Markdown) {
+	// Ahead of the link parser, which would take [[ as a label
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		util.Prioritized(&wikiLinkParser{}, 199),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&wikiHTMLRenderer{}, 500),
+	))
+}
+
+// wikiCSS marks unresolved links and sets the backlinks section apart
+const wikiCSS = `
+		.markdown-content .wiki-missing {
+			color: #dc3545;
+			border-bottom: 1px dashed currentColor;
+		}
+		.markdown-content .backlinks {
+			margin-top: 3rem;
+			padding-top: 1rem;
+			border-top: 1px solid #dee2e6;
+			font-size: 0.9em;
+		}
+		.markdown-content .backlinks h2 {
+			margin-top: 0;
+			border-bottom: 0;
+			font-size: 1.1rem;
+		}`
@@ -0,0 +1,42 @@
+// Package mdconvert converts markdown documents to styled HTML pages, PDF
+// files and PNG/JPEG images. It is the importable API of markdown-to-html;
+// the command line tool and the web server are built on it.
+//
+// A one-off conversion streams from a reader to a writer:
+//
+//	err := mdconvert.ConvertHTML(ctx, input, output, mdconvert.Options{Theme: mdconvert.ThemeDark})
+//
+// Services converting many documents should create a Converter once and
+// share it between goroutines:
+//
+//	c, err := mdconvert.NewConverter(mdconvert.Options{Theme: mdconvert.ThemeLight})
+//	html, err := c.ConvertToHTMLContext(ctx, markdown)
+//
+// PDF output goes through a PDFRenderer. EngineWkhtmltopdf needs the
+// wkhtmltopdf executable; EngineNative is pure Go:
+//
+//	renderer, err := mdconvert.NewPDFRenderer(mdconvert.EngineNative)
+//	err = mdconvert.ConvertPDF(ctx, renderer, input, output, opts)
+//
+// # Versioning
+//
+// The package follows semantic versioning and reports its release in
+// Version. Minor releases only add identifiers and option fields; removing
+// or changing an exported identifier, or changing the meaning of an
+// existing option, requires a new major version. The zero value of every
+// option type keeps working across minor releases.
+package mdconvert
+
+// Version is the release of the mdconvert API. Each minor release since
+// 1.0.0 added one feature's identifiers:
+//
+//	1.1.0  Options.Extensions and Extenders, RegisterExtension, ExtensionNames and the Ext constants
+//	1.2.0  Options.Filters, FilterASTVersion and the FilterFormat constants
+//	1.3.0  ExtMath
+//	1.4.0  Options.Diagrams (opt-in, nil runs no commands) and DiagramCache, DefaultDiagrams, DefaultDiagramCache and EnvPlantUMLJar
+//	1.5.0  Options.References, References, Config, LoadConfig, FindConfig and ExtEmoji
+//	1.6.0  Site, LoadSite, ConvertSite, WikiIssue and DefaultBacklinksTitle
+//	1.7.0  Options.Includes and Includes
+//	1.8.0  Options.CopyButtons
+//	1.9.0  ContextBookRenderer, ConvertBookToHTMLContext and ConvertBookToPDFContext
+const Version = "1.9.0"
@@ -0,0 +1,356 @@
+package mdconvert
+
+import (
+	"context"
+	"io"
+
+	"markdown-to-html/internal/converter"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+)
+
+// Options configures a conversion. The zero value renders the light theme
+// with the default fonts.
+type Options = converter.Options
+
+// FontConfig selects custom typefaces for body text, headings and code
+type FontConfig = converter.FontConfig
+
+// FontFace is one font file resolved from a FontConfig
+type FontFace = converter.FontFace
+
+// Watermark stamps text or an image on every page
+type Watermark = converter.Watermark
+
+// Metadata is the document information stored in PDF and HTML output
+type Metadata = converter.Metadata
+
+// References sets the URL templates for issue, mention and commit links
+type References = converter.References
+
+// Config holds per-project settings read from a configuration file
+type Config = converter.Config
+
+// Includes configures {{< include "path" >}} directives
+type Includes = converter.Includes
+
+// ImageOptions configures image output
+type ImageOptions = converter.ImageOptions
+
+// Book is an ordered list of chapter files combined into one document
+type Book = converter.Book
+
+// Site is a set of pages converted together, resolving [[wiki links]]
+// between them
+type Site = converter.Site
+
+// WikiIssue is a wiki link that did not resolve to exactly one page
+type WikiIssue = converter.WikiIssue
+
+// Converter converts markdown to HTML with fixed options. It is safe for
+// concurrent use and much cheaper per document than the package-level
+// functions.
+type Converter = converter.Converter
+
+// PDFRenderer renders markdown content into a PDF document
+type PDFRenderer = converter.PDFRenderer
+
+// ContextPDFRenderer is implemented by PDF renderers that stop work when a
+// context is cancelled
+type ContextPDFRenderer = converter.ContextPDFRenderer
+
+// ContextBookRenderer is implemented by book renderers that stop work when
+// a context is cancelled
+type ContextBookRenderer = converter.ContextBookRenderer
+
+// BookRenderer is implemented by PDF renderers that can combine chapters
+type BookRenderer = converter.BookRenderer
+
+// ExecutableInfo describes a discovered wkhtmltopdf or wkhtmltoimage executable
+type ExecutableInfo = converter.ExecutableInfo
+
+// Built-in page themes
+const (
+	ThemeLight = converter.ThemeLight
+	ThemeDark  = converter.ThemeDark
+)
+
+// PDF engine names accepted by NewPDFRenderer
+const (
+	EngineWkhtmltopdf = converter.EngineWkhtmltopdf
+	EngineNative      = converter.EngineNative
+)
+
+// Image formats accepted by ImageOptions
+const (
+	ImagePNG  = converter.ImagePNG
+	ImageJPEG = converter.ImageJPEG
+)
+
+// Watermark positions
+const (
+	WatermarkCenter = converter.WatermarkCenter
+	WatermarkTop    = converter.WatermarkTop
+	WatermarkBottom = converter.WatermarkBottom
+)
+
+// Defaults applied to unset options
+const (
+	DefaultImageWidth        = converter.DefaultImageWidth
+	DefaultWatermarkRotation = converter.DefaultWatermarkRotation
+	DefaultTOCTitle          = converter.DefaultTOCTitle
+	DefaultCreator           = converter.DefaultCreator
+	DefaultBacklinksTitle    = converter.DefaultBacklinksTitle
+)
+
+// Environment variables that override wkhtmltopdf discovery
+const (
+	EnvWkhtmltopdfBin = converter.EnvWkhtmltopdfBin
+	EnvWkhtmltopdfDir = converter.EnvWkhtmltopdfDir
+)
+
+// ConfigFileName is the per-project configuration file looked up by
+// FindConfig
+const ConfigFileName = converter.ConfigFileName
+
+// EnvPlantUMLJar names a local PlantUML jar used by DefaultDiagrams
+const EnvPlantUMLJar = converter.EnvPlantUMLJar
+
+// Themes returns the names of the built-in page themes
+func Themes() []string {
+	return converter.Themes()
+}
+
+// DefaultDiagrams returns the built-in diagram commands by fence language.
+// Options.Diagrams runs no commands when nil; set it to DefaultDiagrams()
+// to render Graphviz and PlantUML fences.
+func DefaultDiagrams() map[string]string {
+	return converter.DefaultDiagrams()
+}
+
+// DefaultDiagramCache returns the directory diagrams are cached in when
+// Options.DiagramCache is empty
+func DefaultDiagramCache() string {
+	return converter.DefaultDiagramCache()
+}
+
+// NewConverter prepares a reusable Converter for opts
+func NewConverter(opts Options) (*Converter, error) {
+	return converter.NewConverter(opts)
+}
+
+// ConvertToHTML converts markdown to a full HTML page
+func ConvertToHTML(ctx context.Context, markdown string, opts Options) (string, error) {
+	return converter.ConvertToHTMLContext(ctx, markdown, opts)
+}
+
+// ConvertHTML reads markdown from r and streams the HTML page to w
+func ConvertHTML(ctx context.Context, r io.Reader, w io.Writer, opts Options) error {
+	return converter.ConvertHTML(ctx, r, w, opts)
+}
+
+// NewPDFRenderer returns the PDF renderer for the named engine; an empty
+// name selects EngineWkhtmltopdf
+func NewPDFRenderer(engine string) (PDFRenderer, error) {
+	return converter.NewPDFRenderer(engine)
+}
+
+// RenderPDF renders markdown with renderer and writes the PDF to w. Nothing
+// is written to w on failure.
+func RenderPDF(ctx context.Context, renderer PDFRenderer, w io.Writer, markdown string, opts Options) error {
+	return converter.RenderPDFContext(ctx, renderer, w, markdown, opts)
+}
+
+// ConvertPDF reads markdown from r and writes the PDF produced by renderer
+// to w. Nothing is written to w on failure.
+func ConvertPDF(ctx context.Context, renderer PDFRenderer, r io.Reader, w io.Writer, opts Options) error {
+	return converter.ConvertPDF(ctx, renderer, r, w, opts)
+}
+
+// ConvertToPDFFile renders markdown with renderer into the file at outputPath
+func ConvertToPDFFile(ctx context.Context, renderer PDFRenderer, markdown string, outputPath string, opts Options) error {
+	return converter.ConvertToPDFContext(ctx, renderer, markdown, outputPath, opts)
+}
+
+// RenderImage renders markdown to an image with wkhtmltoimage and writes it
+// to w. Nothing is written to w on failure.
+func RenderImage(ctx context.Context, w io.Writer, markdown string, opts Options, img ImageOptions) error {
+	return converter.RenderImageContext(ctx, w, markdown, opts, img)
+}
+
+// ConvertImage reads markdown from r and writes the rendered image to w
+func ConvertImage(ctx context.Context, r io.Reader, w io.Writer, opts Options, img ImageOptions) error {
+	return converter.ConvertImage(ctx, r, w, opts, img)
+}
+
+// ConvertFiles converts markdown files to HTML pages in outputDir, sharing
+// one Converter across the batch. converted, if not nil, is called after
+// each file is written. It stops at the first error.
+func ConvertFiles(ctx context.Context, inputFiles []string, outputDir string, opts Options, converted func(inputFile, outputFile string)) error {
+	return converter.ConvertFiles(ctx, inputFiles, outputDir, opts, converted)
+}
+
+// LoadSite collects the markdown files under root into a Site
+func LoadSite(root string) (*Site, error) {
+	return converter.LoadSite(root)
+}
+
+// ConvertSite converts the pages of site to HTML files in outputDir,
+// resolving [[wiki links]] and adding backlinks sections. Links that match
+// no page or several are returned as issues. converted, if not nil, is
+// called after each file is written.
+func ConvertSite(ctx context.Context, site *Site, outputDir string, opts Options, converted func(inputFile, outputFile string)) ([]WikiIssue, error) {
+	return converter.ConvertSite(ctx, site, outputDir, opts, converted)
+}
+
+// LoadSummary reads a SUMMARY.md manifest into a Book
+func LoadSummary(path string) (*Book, error) {
+	return converter.LoadSummary(path)
+}
+
+// ConvertBookToHTML combines the chapters of book into one HTML page
+func ConvertBookToHTML(book *Book, opts Options) (string, error) {
+	return converter.ConvertBookToHTML(book, opts)
+}
+
+// ConvertBookToHTMLContext is ConvertBookToHTML with cancellation and the
+// per-document timeout from opts, which covers the whole book
+func ConvertBookToHTMLContext(ctx context.Context, book *Book, opts Options) (string, error) {
+	return converter.ConvertBookToHTMLContext(ctx, book, opts)
+}
+
+// ConvertBookToPDF combines the chapters of book into one PDF file with a
+// title page, table of contents and bookmarks
+func ConvertBookToPDF(renderer PDFRenderer, book *Book, outputPath string, opts Options) error {
+	return converter.ConvertBookToPDF(renderer, book, outputPath, opts)
+}
+
+// ConvertBookToPDFContext is ConvertBookToPDF with cancellation and the
+// per-document timeout from opts, which covers the whole book
+func ConvertBookToPDFContext(ctx context.Context, renderer PDFRenderer, book *Book, outputPath string, opts Options) error {
+	return converter.ConvertBookToPDFContext(ctx, renderer, book, outputPath, opts)
+}
+
+// LoadConfig reads a configuration file
+func LoadConfig(path string) (*Config, error) {
+	return converter.LoadConfig(path)
+}
+
+// FindConfig returns the path of the nearest ConfigFileName in dir or its
+// parents, or an empty string if there is none
+func FindConfig(dir string) string {
+	return converter.FindConfig(dir)
+}
+
+// ParseHeadingLevels parses a comma separated list of heading levels such
+// as "h1,h2" or "1,2"
+func ParseHeadingLevels(s string) ([]int, error) {
+	return converter.ParseHeadingLevels(s)
+}
+
+// SetWkhtmltopdfPath overrides discovery with an explicit wkhtmltopdf
+// binary; wkhtmltoimage is looked up next to it
+func SetWkhtmltopdfPath(path string) {
+	converter.SetWkhtmltopdfPath(path)
+}
+
+// IsWkhtmltopdfInstalled reports whether wkhtmltopdf can be found
+func IsWkhtmltopdfInstalled() bool {
+	return converter.IsWkhtmltopdfInstalled()
+}
+
+// IsWkhtmltoimageInstalled reports whether wkhtmltoimage can be found
+func IsWkhtmltoimageInstalled() bool {
+	return converter.IsWkhtmltoimageInstalled()
+}
+
+// DetectWkhtmltopdf locates wkhtmltopdf and reports its version
+func DetectWkhtmltopdf() (*ExecutableInfo, error) {
+	return converter.DetectWkhtmltopdf()
+}
+
+// DetectWkhtmltoimage locates wkhtmltoimage and reports its version
+func DetectWkhtmltoimage() (*ExecutableInfo, error) {
+	return converter.DetectWkhtmltoimage()
+}
+
+// InstallInstructions explains how to install wkhtmltopdf on this platform
+func InstallInstructions() string {
+	return converter.GetWkhtmltopdfInstallInstructions()
+}
+
+// CDNResources returns the external assets the generated HTML loads
+func CDNResources() []string {
+	return converter.CDNResources()
+}
+
+// NativeFonts reports the TrueType font file the native engine uses for
+// each role
+func NativeFonts() map[string]string {
+	return converter.NativeFonts()
+}
+
+// Built-in extensions that can be enabled by name in Options.Extensions
+const (
+	ExtFootnote       = converter.ExtFootnote
+	ExtDefinitionList = converter.ExtDefinitionList
+	ExtTypographer    = converter.ExtTypographer
+	ExtCJK            = converter.ExtCJK
+	ExtMath           = converter.ExtMath
+	ExtEmoji          = converter.ExtEmoji
+)
+
+// FilterASTVersion is the version of the JSON document exchanged with
+// Options.Filters commands
+const FilterASTVersion = converter.FilterASTVersion
+
+// Output formats passed to filters as their last argument
+const (
+	FilterFormatHTML = converter.FilterFormatHTML
+	FilterFormatPDF  = converter.FilterFormatPDF
+)
+
+// ExtenderFunc adapts a function to goldmark.Extender
+type ExtenderFunc = converter.ExtenderFunc
+
+// RegisterExtension makes ext available under name for Options.Extensions
+// and the --ext flag. Registering an existing name replaces it.
+func RegisterExtension(name string, ext goldmark.Extender) {
+	converter.RegisterExtension(name, ext)
+}
+
+// ExtensionNames returns the sorted names of all registered extensions
+func ExtensionNames() []string {
+	return converter.ExtensionNames()
+}
+
+// ParseExtensions parses a comma separated list of extension names and
+// checks that each one is registered
+func ParseExtensions(s string) ([]string, error) {
+	return converter.ParseExtensions(s)
+}
+
+// WithParserOptions returns an extension that applies goldmark parser options
+func WithParserOptions(opts ...parser.Option) goldmark.Extender {
+	return converter.WithParserOptions(opts...)
+}
+
+// WithRendererOptions returns an extension that applies goldmark HTML
+// renderer options
+func WithRendererOptions(opts ...renderer.Option) goldmark.Extender {
+	return converter.WithRendererOptions(opts...)
+}
+
+// WithASTTransformer returns an extension that runs t on every parsed
+// document; lower priorities run first
+func WithASTTransformer(t parser.ASTTransformer, priority int) goldmark.Extender {
+	return converter.WithASTTransformer(t, priority)
+}
+
+// WithNodeRenderer returns an extension that renders nodes to HTML with r;
+// lower priorities win for the same node kind
+func WithNodeRenderer(r renderer.NodeRenderer, priority int) goldmark.Extender {
+	return converter.WithNodeRenderer(r, priority)
+}
@@ -17,7 +17,7 @@ go mod tidy
 
 REM Run the application with example
 echo Running with example file...
-go run cmd/main.go examples/sample.md output/sample.html
+go run ./cmd examples/sample.md output/sample.html
 
 if errorlevel 1 (
     echo Error: Application failed to run
@@ -13,6 +13,6 @@ echo.
 echo Durdurmak için Ctrl+C tuşlayın
 echo.
 
-go run cmd/web/main.go
+go run ./cmd/web
 
 pause